@@ -0,0 +1,162 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"main.go/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttendees(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := storage.OpenFileStorage(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	handler.NewHandlerWithStore(store).Register(mux)
+
+	// do - sends request through mux and decodes response
+	do := func(method, target, contentType string, body []byte) (int, handler.ResultResponse) {
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		var response handler.ResultResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	// day - returns titles of events of user on February 1 2022
+	day := func(userID string) []string {
+		_, response := do("GET", "/events_for_day?date=2022-02-01&user_id="+userID, "", nil)
+
+		var titles []string
+		for _, event := range response.Result {
+			titles = append(titles, event.Title)
+		}
+		return titles
+	}
+
+	event := model.Event{
+		UserID: 1,
+		Title:  "planning",
+		Date:   model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)},
+		Attendees: []model.Attendee{
+			{UserID: 2, Status: model.Accepted},
+			{UserID: 3},
+		},
+	}
+
+	code, response := do("POST", "/create_event", "", jsonEncode(event))
+	if code != http.StatusOK {
+		t.Fatalf("event is not created\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+	for _, attendee := range response.Result[0].Attendees {
+		if attendee.Status != model.NeedsAction {
+			t.Errorf("owner should not answer for attendee\nShould: %s\nGot: %v", model.NeedsAction, attendee)
+		}
+	}
+
+	if got := day("2"); len(got) != 1 || got[0] != "planning" {
+		t.Errorf("invited event should be in calendar of attendee\nShould: [planning]\nGot: %v", got)
+	}
+
+	code, _ = do("PUT", "/users/2/invitations/1/1", "", []byte(`{"status": "accepted"}`))
+	if code != http.StatusOK {
+		t.Errorf("answer is not saved\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+
+	code, _ = do("POST", "/respond_event", "application/x-www-form-urlencoded",
+		[]byte("user_id=3&owner_id=1&event_id=1&status=declined"))
+	if code != http.StatusOK {
+		t.Errorf("answer is not saved\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+
+	if got := day("3"); len(got) != 0 {
+		t.Errorf("declined event should not be in calendar\nShould: []\nGot: %v", got)
+	}
+
+	code, _ = do("PUT", "/users/4/invitations/1/1", "", []byte(`{"status": "accepted"}`))
+	if code != http.StatusNotFound {
+		t.Errorf("user who is not invited can not answer\nShould: %d\nGot: %d", http.StatusNotFound, code)
+	}
+
+	code, _ = do("PUT", "/users/2/invitations/1/1", "", []byte(`{"status": "maybe"}`))
+	if code != http.StatusBadRequest {
+		t.Errorf("unknown status\nShould: %d\nGot: %d", http.StatusBadRequest, code)
+	}
+
+	// attendee has no event with such id, only owner can edit it
+	event.Title = "attendee edit"
+	event.UserID = 0
+	event.Attendees = nil
+	// both answers changed version of event
+	event.Version = 3
+	code, _ = do("PUT", "/users/2/events/1", "", jsonEncode(event))
+	if code != http.StatusNotFound {
+		t.Errorf("attendee can not edit event\nShould: %d\nGot: %d", http.StatusNotFound, code)
+	}
+
+	event.Title = "planning"
+	event.UserID = 1
+	event.EventID = 1
+	event.Attendees = []model.Attendee{{UserID: 2}, {UserID: 4}}
+	code, response = do("POST", "/update_event", "", jsonEncode(event))
+	if code != http.StatusOK {
+		t.Fatalf("event is not updated\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+
+	want := []model.Attendee{{UserID: 2, Status: model.Accepted}, {UserID: 4, Status: model.NeedsAction}}
+	if got := response.Result[0].Attendees; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("answers should be kept\nShould: %v\nGot: %v", want, got)
+	}
+
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// invitations are restored from disk
+	store, err = storage.OpenFileStorage(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	mux = http.NewServeMux()
+	handler.NewHandlerWithStore(store).Register(mux)
+
+	for userID, want := range map[string]int{"2": 1, "3": 0, "4": 1} {
+		_, response = do("GET", "/users/"+userID+"/invitations", "", nil)
+		if len(response.Result) != want {
+			t.Errorf("wrong invitations of user %s\nShould: %d\nGot: %v", userID, want, response.Result)
+		}
+	}
+
+	code, _ = do("POST", "/create_event", "", []byte(`{"user_id": 1, "date": "2022-02-01T10:00:00Z", "attendees": [{"user_id": 1}]}`))
+	if code != http.StatusBadRequest {
+		t.Errorf("owner can not be attendee\nShould: %d\nGot: %d", http.StatusBadRequest, code)
+	}
+
+	code, response = do("POST", "/create_event", "application/x-www-form-urlencoded",
+		[]byte("user_id=1&date=2022-02-01T12:00&attendees=2,3"))
+	if code != http.StatusOK || len(response.Result) != 1 || len(response.Result[0].Attendees) != 2 {
+		t.Errorf("attendees from form\nShould: 2 attendees\nGot: %d %v", code, response)
+	}
+
+	if got := strings.Join(day("2"), ","); got != "planning," {
+		t.Errorf("attendee should see both events\nShould: planning,\nGot: %s", got)
+	}
+}
@@ -0,0 +1,158 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditAndTrash(t *testing.T) {
+	dir := t.TempDir()
+	store := openFileStorage(t, dir)
+
+	var mux *http.ServeMux
+
+	// serve - sends request through mux and returns recorder
+	serve := func(method, target, ifMatch string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// do - sends request and decodes events from response
+	do := func(method, target, ifMatch string, body []byte) (int, []model.Event) {
+		w := serve(method, target, ifMatch, body)
+
+		var response handler.ResultResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Result
+	}
+
+	// history - returns actions of event history with their actors
+	history := func(target string) (int, []string) {
+		w := serve("GET", target, "", nil)
+
+		var response handler.HistoryResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+		var actions []string
+		for _, record := range response.Result {
+			if record.At.IsZero() || (record.Before == nil && record.After == nil) {
+				t.Errorf("record should have time and state of event\nGot: %+v", record)
+			}
+			actions = append(actions, fmt.Sprintf("%s:%d", record.Action, record.ActorID))
+		}
+		return w.Code, actions
+	}
+
+	mux = http.NewServeMux()
+	handler.NewHandlerWithStore(store).Register(mux)
+
+	event := model.Event{
+		UserID:    1,
+		Title:     "retro",
+		Date:      model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)},
+		Attendees: []model.Attendee{{UserID: 2}},
+	}
+	if code, _ := do("POST", "/create_event", "", jsonEncode(event)); code != http.StatusOK {
+		t.Fatalf("event is not created\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+
+	event.EventID = 1
+	event.Title = "moved retro"
+	event.Version = 1
+	if code, _ := do("POST", "/update_event", "", jsonEncode(event)); code != http.StatusOK {
+		t.Fatalf("event is not updated\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+
+	if code, _ := do("PUT", "/users/2/invitations/1/1", "", []byte(`{"status": "accepted"}`)); code != http.StatusOK {
+		t.Fatalf("answer is not saved\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+
+	missing := model.Event{UserID: 1, EventID: 5, Version: 1}
+	if code, _ := do("POST", "/delete_event", "", jsonEncode(missing)); code != http.StatusNotFound {
+		t.Errorf("deletion of missing event\nShould: %d\nGot: %d", http.StatusNotFound, code)
+	}
+
+	// request names only ids, response has the deleted event
+	code, deleted := do("POST", "/delete_event", `"3"`, []byte(`{"user_id": 1, "event_id": 1}`))
+	if code != http.StatusOK || len(deleted) != 1 || deleted[0].Title != "moved retro" {
+		t.Fatalf("deleted event should be returned\nShould: %d moved retro\nGot: %d %v", http.StatusOK, code, deleted)
+	}
+
+	if _, events := do("GET", "/events_for_day?user_id=1&date=2022-02-01", "", nil); len(events) != 0 {
+		t.Errorf("deleted event should leave calendar\nShould: []\nGot: %v", events)
+	}
+	if _, events := do("GET", "/trash?user_id=1", "", nil); len(events) != 1 || events[0].EventID != 1 {
+		t.Errorf("deleted event should be in trash\nShould: [1]\nGot: %v", events)
+	}
+
+	event.EventID = 1
+	if code, _ := do("POST", "/create_event", "", jsonEncode(event)); code != http.StatusServiceUnavailable {
+		t.Errorf("id of event in trash is taken\nShould: %d\nGot: %d", http.StatusServiceUnavailable, code)
+	}
+
+	want := "create:1,update:1,respond:2,delete:1"
+	if code, actions := history("/events/1/history?user_id=1"); code != http.StatusOK || strings.Join(actions, ",") != want {
+		t.Errorf("wrong history\nShould: %d %s\nGot: %d %v", http.StatusOK, want, code, actions)
+	}
+
+	if code, _ := history("/users/1/events/7/history"); code != http.StatusNotFound {
+		t.Errorf("history of unknown event\nShould: %d\nGot: %d", http.StatusNotFound, code)
+	}
+
+	// store is dropped without Close, trash and history are restored from write-ahead log
+	store = openFileStorage(t, dir)
+	mux = http.NewServeMux()
+	handler.NewHandlerWithStore(store).Register(mux)
+
+	code, restored := do("POST", "/users/1/events/1/restore", "", nil)
+	if code != http.StatusOK || len(restored) != 1 || restored[0].Title != "moved retro" || restored[0].Version != 4 {
+		t.Fatalf("event is not restored\nShould: %d moved retro version 4\nGot: %d %v", http.StatusOK, code, restored)
+	}
+	if restored[0].Attendees[0].Status != model.Accepted {
+		t.Errorf("answers should be restored\nShould: %s\nGot: %v", model.Accepted, restored[0].Attendees)
+	}
+
+	if code, _ := do("POST", "/users/1/events/1/restore", "", nil); code != http.StatusNotFound {
+		t.Errorf("event is restored twice\nShould: %d\nGot: %d", http.StatusNotFound, code)
+	}
+
+	if _, events := do("GET", "/events_for_day?user_id=2&date=2022-02-01", "", nil); len(events) != 1 {
+		t.Errorf("restored event should be back in calendars\nShould: 1 event\nGot: %v", events)
+	}
+
+	// snapshot keeps trash and history too
+	if code, _ := do("DELETE", "/users/1/events/1", `"4"`, nil); code != http.StatusOK {
+		t.Fatalf("event is not deleted\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store = openFileStorage(t, dir)
+	defer store.Close()
+	mux = http.NewServeMux()
+	handler.NewHandlerWithStore(store).Register(mux)
+
+	want = "create:1,update:1,respond:2,delete:1,restore:1,delete:1"
+	if _, actions := history("/users/1/events/1/history"); strings.Join(actions, ",") != want {
+		t.Errorf("wrong history after snapshot\nShould: %s\nGot: %v", want, actions)
+	}
+	if _, events := do("GET", "/users/1/trash", "", nil); len(events) != 1 {
+		t.Errorf("trash is lost after snapshot\nShould: 1 event\nGot: %v", events)
+	}
+}
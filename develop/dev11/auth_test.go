@@ -0,0 +1,114 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/auth"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"main.go/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuth(t *testing.T) {
+	authenticator := auth.New("secret", map[string]int{"key-of-user-9": 9})
+
+	mux := http.NewServeMux()
+	handler.NewHandlerWithStore(storage.NewEventStorage(), handler.WithAuth(authenticator)).Register(mux)
+
+	token, err := authenticator.Issue(5, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, _ := authenticator.Issue(5, -time.Minute)
+	foreign, _ := auth.New("other secret", nil).Issue(5, time.Hour)
+
+	// do - sends request with header and decodes response
+	do := func(method, target string, body []byte, header, value string) (int, handler.ResultResponse) {
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		var response handler.ResultResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	bearer := "Bearer " + token
+	event := model.Event{Title: "meeting", Date: model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)}}
+
+	t.Run("credentials", func(t *testing.T) {
+		cases := []struct {
+			name, header, value string
+			want                int
+		}{
+			{name: "missing", want: http.StatusUnauthorized},
+			{name: "expired", header: "Authorization", value: "Bearer " + expired, want: http.StatusUnauthorized},
+			{name: "foreign secret", header: "Authorization", value: "Bearer " + foreign, want: http.StatusUnauthorized},
+			{name: "unknown key", header: "X-API-Key", value: "key-of-user-10", want: http.StatusUnauthorized},
+			{name: "token", header: "Authorization", value: bearer, want: http.StatusOK},
+			{name: "api key", header: "X-API-Key", value: "key-of-user-9", want: http.StatusOK},
+		}
+
+		for _, c := range cases {
+			code, _ := do("GET", "/events_for_day?date=2022-02-01", nil, c.header, c.value)
+			if code != c.want {
+				t.Errorf("%s: wrong status\nShould: %d\nGot: %d", c.name, c.want, code)
+			}
+		}
+
+		if code, _ := do("GET", "/openapi.json", nil, "", ""); code != http.StatusOK {
+			t.Errorf("OpenAPI document should be public\nShould: %d\nGot: %d", http.StatusOK, code)
+		}
+	})
+
+	t.Run("acting user", func(t *testing.T) {
+		code, response := do("POST", "/create_event", jsonEncode(event), "Authorization", bearer)
+		if code != http.StatusOK || len(response.Result) != 1 || response.Result[0].UserID != 5 {
+			t.Fatalf("event should get user from token\nShould: 5\nGot: %d %v", code, response)
+		}
+
+		code, response = do("GET", "/events_for_day?date=2022-02-01", nil, "Authorization", bearer)
+		if code != http.StatusOK || len(response.Result) != 1 {
+			t.Errorf("user should see own events\nShould: 1 event\nGot: %d %v", code, response)
+		}
+
+		code, response = do("GET", "/events_for_day?date=2022-02-01", nil, "X-API-Key", "key-of-user-9")
+		if code != http.StatusOK || len(response.Result) != 0 {
+			t.Errorf("user should not see events of others\nShould: 0 events\nGot: %d %v", code, response)
+		}
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		cases := []struct {
+			method, target string
+			body           model.Event
+		}{
+			{method: "GET", target: "/events_for_day?user_id=9&date=2022-02-01"},
+			{method: "GET", target: "/users/9/events/1"},
+			{method: "DELETE", target: "/users/9/events/1"},
+			{method: "POST", target: "/create_event", body: model.Event{UserID: 9, Date: event.Date}},
+			{method: "POST", target: "/delete_event", body: model.Event{UserID: 9, EventID: 1, Date: event.Date}},
+		}
+
+		for _, c := range cases {
+			var body []byte
+			if c.method == "POST" {
+				body = jsonEncode(c.body)
+			}
+
+			code, _ := do(c.method, c.target, body, "Authorization", bearer)
+			if code != http.StatusForbidden {
+				t.Errorf("%s %s should be forbidden\nShould: %d\nGot: %d", c.method, c.target, http.StatusForbidden, code)
+			}
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	dir := t.TempDir()
+	store := openFileStorage(t, dir)
+
+	mux := http.NewServeMux()
+	handler.NewHandlerWithStore(store).Register(mux)
+
+	// batch - sends operations and returns status code with results
+	batch := func(atomic bool, ops ...model.Operation) (int, []handler.BatchResult) {
+		body, _ := json.Marshal(handler.BatchRequest{Atomic: atomic, Operations: ops})
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/batch", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		var response handler.BatchResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Results
+	}
+
+	// statuses - returns statuses of results
+	statuses := func(results []handler.BatchResult) []int {
+		var codes []int
+		for _, result := range results {
+			codes = append(codes, result.Status)
+		}
+		return codes
+	}
+
+	// equal - compares statuses
+	equal := func(a, b []int) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	event := func(id int, title string, version int) model.Event {
+		return model.Event{
+			UserID:  1,
+			EventID: id,
+			Title:   title,
+			Date:    model.Date{Time: time.Date(2022, 2, id, 10, 0, 0, 0, time.UTC)},
+			Version: version,
+		}
+	}
+
+	t.Run("best effort", func(t *testing.T) {
+		code, results := batch(false,
+			model.Operation{Op: model.OpCreate, Event: event(1, "first", 0)},
+			model.Operation{Op: model.OpCreate, Event: model.Event{UserID: 1, EventID: -1}},
+			model.Operation{Op: model.OpCreate, Event: event(2, "second", 0)},
+			model.Operation{Op: model.OpUpdate, Event: event(2, "second updated", 7)},
+			model.Operation{Op: model.OpDelete, Event: event(9, "", 1)},
+			model.Operation{Op: model.OpUpdate, Event: event(1, "first updated", 0)},
+			model.Operation{Op: "move", Event: event(1, "", 1)},
+		)
+
+		want := []int{200, 400, 200, 409, 404, 428, 400}
+		if code != http.StatusOK || !equal(statuses(results), want) {
+			t.Fatalf("wrong results\nShould: %d %v\nGot: %d %v", http.StatusOK, want, code, results)
+		}
+		if results[0].Event == nil || results[0].Event.Version != 1 {
+			t.Errorf("created event should be returned with version\nShould: 1\nGot: %v", results[0].Event)
+		}
+	})
+
+	t.Run("atomic rollback", func(t *testing.T) {
+		code, results := batch(true,
+			model.Operation{Op: model.OpCreate, Event: event(3, "third", 0)},
+			model.Operation{Op: model.OpUpdate, Event: event(1, "first updated", 1)},
+			model.Operation{Op: model.OpDelete, Event: event(2, "", 1)},
+			model.Operation{Op: model.OpUpdate, Event: event(2, "second updated", 1)},
+		)
+
+		want := []int{424, 424, 424, 404}
+		if code != http.StatusNotFound || !equal(statuses(results), want) {
+			t.Fatalf("wrong results\nShould: %d %v\nGot: %d %v", http.StatusNotFound, want, code, results)
+		}
+
+		events := monthEvents(t, store, event(1, "", 0))
+		if len(events) != 2 || events[0].Title != "first" || events[0].Version != 1 || events[1].Title != "second" {
+			t.Errorf("failed batch should change nothing\nShould: [first second]\nGot: %v", events)
+		}
+	})
+
+	t.Run("atomic rejects invalid operation", func(t *testing.T) {
+		code, results := batch(true,
+			model.Operation{Op: model.OpCreate, Event: event(3, "third", 0)},
+			model.Operation{Op: model.OpDelete, Event: event(2, "", 0)},
+		)
+
+		want := []int{424, 428}
+		if code != http.StatusPreconditionRequired || !equal(statuses(results), want) {
+			t.Fatalf("wrong results\nShould: %d %v\nGot: %d %v", http.StatusPreconditionRequired, want, code, results)
+		}
+	})
+
+	t.Run("atomic commit", func(t *testing.T) {
+		code, results := batch(true,
+			model.Operation{Op: model.OpCreate, Event: event(3, "third", 0)},
+			model.Operation{Op: model.OpUpdate, Event: event(3, "third updated", 1)},
+			model.Operation{Op: model.OpDelete, Event: event(2, "", 1)},
+		)
+
+		want := []int{200, 200, 200}
+		if code != http.StatusOK || !equal(statuses(results), want) {
+			t.Fatalf("wrong results\nShould: %d %v\nGot: %d %v", http.StatusOK, want, code, results)
+		}
+		if results[1].Event.Version != 2 {
+			t.Errorf("operation should see previous ones\nShould: 2\nGot: %d", results[1].Event.Version)
+		}
+	})
+
+	for name, body := range map[string]string{
+		"no operations": `{"atomic": true, "operations": []}`,
+		"broken json":   `{"operations": [`,
+	} {
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/batch", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s\nShould: %d\nGot: %d", name, http.StatusBadRequest, w.Code)
+		}
+	}
+
+	// transaction is restored from write-ahead log as a whole
+	reopened := openFileStorage(t, dir)
+	defer reopened.Close()
+
+	events := monthEvents(t, reopened, event(1, "", 0))
+	if len(events) != 2 || events[0].Title != "first" || events[1].Title != "third updated" || events[1].Version != 2 {
+		t.Errorf("wrong events after reopen\nShould: [first third updated]\nGot: %v", events)
+	}
+}
@@ -1,12 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"main.go/internal/auth"
 	"main.go/internal/config"
+	"main.go/internal/config/helper"
 	"main.go/internal/handler"
+	"main.go/internal/metrics"
+	"main.go/internal/model"
+	"main.go/internal/reminder"
+	"main.go/internal/rpc"
+	"main.go/internal/service"
+	"main.go/internal/storage"
+	"main.go/internal/stream"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 /*
@@ -38,6 +55,10 @@ GET /events_for_month
 */
 
 func main() {
+	tokenUser := flag.Int("token", 0, "print bearer token of user and exit")
+	tokenTTL := flag.Duration("token-ttl", 24*time.Hour, "lifetime of token printed with -token")
+	flag.Parse()
+
 	// reading config
 	cfg, err := config.ReadConfigYaml("config.yaml")
 	if err != nil {
@@ -45,24 +66,244 @@ func main() {
 		os.Exit(1)
 	}
 
+	authenticator := auth.New(cfg.Auth.Secret, cfg.Auth.APIKeys)
+
+	if *tokenUser != 0 {
+		token, err := authenticator.Issue(*tokenUser, *tokenTTL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(token)
+		return
+	}
+
+	logger, err := newLogger(cfg.Log, os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// messages of log package go to the same output
+	slog.SetDefault(logger)
+
+	if !authenticator.Enabled() {
+		logger.Warn("authentication is disabled, any client can act as any user")
+	}
+
 	// creating new Mux
 	mux := http.NewServeMux()
 
+	// changes of events for stream clients
+	broker := stream.NewBroker(cfg.Calendar.StreamHistory)
+
+	// opening data store
+	store, err := openStore(cfg, broker)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// reminders are sent only when webhook is configured
+	scheduler, err := startReminders(cfg.Reminders, store)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// working hours of users for slot suggestions
+	hours, err := workingHours(cfg.Calendar)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// business logic shared by HTTP and JSON-RPC front-ends
+	eventService := service.New(store, hours)
+
 	// creating new handler
-	api := handler.NewHandler()
+	api := handler.NewHandlerWithService(eventService, handler.WithAuth(authenticator), handler.WithStream(broker),
+		handler.WithMaxBodyBytes(cfg.Limits.MaxBodyBytes), handler.WithIdempotencyKeys(cfg.Limits.IdempotencyKeys))
 
 	// register all routes
 	api.Register(mux)
 
-	// Logger middleware
-	muxWithLogger := handler.Logging(mux)
+	// JSON-RPC 2.0 front-end with the same credentials and body limit, rate limiter below covers it too
+	mux.Handle("POST /rpc", api.Authenticate(rpc.NewServer(eventService, rpc.WithMaxBodyBytes(cfg.Limits.MaxBodyBytes))))
+
+	// metrics of requests and data store, they need the same credentials as API when authentication is enabled
+	registry := metrics.NewRegistry()
+	registerStoreMetrics(registry, store)
+	mux.Handle("GET /metrics", api.Authenticate(registry))
+
+	// requests over rate of client get 429, they are still logged and counted
+	var limiter *handler.RateLimiter
+	if cfg.Limits.RequestsPerSecond > 0 {
+		limiter = handler.NewRateLimiter(cfg.Limits.RequestsPerSecond, cfg.Limits.Burst)
+	}
+
+	// Metrics and logger middleware
+	muxWithLogger := handler.Logging(logger, handler.Metrics(registry, handler.RateLimit(limiter, mux)))
+
+	server := newServer(cfg.HttpServer, muxWithLogger)
+	// streams never finish by themselves, so they are closed before waiting for requests
+	server.RegisterOnShutdown(broker.Close)
+
+	// stop signal cancels context
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server is listening on: %s\n", server.Addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	// failed - server stopped by itself, e.g. port is already in use
+	var failed bool
+
+	select {
+	case err = <-serveErr:
+		log.Println(err)
+		failed = !errors.Is(err, http.ErrServerClosed)
+	case <-ctx.Done():
+		log.Println("Server is shutting down")
+		shutdown(server, cfg.HttpServer.ShutdownTimeout)
+	}
+
+	if scheduler != nil {
+		helper.Closer(scheduler)
+	}
+
+	// file store writes final snapshot
+	if closer, ok := store.(io.Closer); ok {
+		helper.Closer(closer)
+	}
+
+	// supervisors should not take failed start for clean shutdown
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// newLogger - creates logger with level and format from config
+func newLogger(cfg config.Log, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.Level != "" {
+		err := level.UnmarshalText([]byte(cfg.Level))
+		if err != nil {
+			return nil, fmt.Errorf("wrong log level %q: %v", cfg.Level, err)
+		}
+	}
 
-	// ip:port
-	addr := fmt.Sprintf("%s:%s", cfg.HttpServer.IP, cfg.HttpServer.Port)
+	opts := &slog.HandlerOptions{Level: level}
 
-	log.Printf("Server is listening on: %s\n", addr)
-	err = http.ListenAndServe(addr, muxWithLogger)
+	switch cfg.Format {
+	case "", "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", cfg.Format)
+	}
+}
+
+// startReminders - starts reminder scheduler if webhook is set in config
+func startReminders(cfg config.Reminders, store service.Store) (*reminder.Scheduler, error) {
+	if cfg.WebhookURL == "" {
+		return nil, nil
+	}
+
+	source, ok := store.(reminder.Source)
+	if !ok {
+		return nil, errors.New("data store does not support reminders")
+	}
+
+	scheduler, err := reminder.New(source, cfg.WebhookURL, cfg.StateFile)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	scheduler.Start(interval)
+
+	return scheduler, nil
+}
+
+// workingHours - parses working hours of users from config
+func workingHours(cfg config.Calendar) (service.Option, error) {
+	defaults := model.DefaultWorkingHours()
+	if cfg.DefaultWorkingHours.Start != "" || cfg.DefaultWorkingHours.End != "" {
+		var err error
+		hours := cfg.DefaultWorkingHours
+		defaults, err = model.ParseWorkingHours(hours.Start, hours.End, hours.Days, hours.TZ)
+		if err != nil {
+			return nil, fmt.Errorf("default working hours: %v", err)
+		}
+	}
+
+	users := make(map[int]model.WorkingHours, len(cfg.WorkingHours))
+	for userID, hours := range cfg.WorkingHours {
+		parsed, err := model.ParseWorkingHours(hours.Start, hours.End, hours.Days, hours.TZ)
+		if err != nil {
+			return nil, fmt.Errorf("working hours of user %d: %v", userID, err)
+		}
+		users[userID] = parsed
+	}
+
+	return service.WithWorkingHours(defaults, users), nil
+}
+
+// registerStoreMetrics - exposes size of data store
+func registerStoreMetrics(registry *metrics.Registry, store service.Store) {
+	registry.NewGaugeFunc("calendar_users", "Number of users with events.", func() float64 {
+		users, _ := store.Size()
+		return float64(users)
+	})
+	registry.NewGaugeFunc("calendar_events", "Number of stored events, recurring event is counted once.", func() float64 {
+		_, events := store.Size()
+		return float64(events)
+	})
+}
+
+// newServer - creates http server with address and limits from config
+func newServer(cfg config.HttpServer, h http.Handler) *http.Server {
+	return &http.Server{
+		// ip:port
+		Addr:              fmt.Sprintf("%s:%s", cfg.IP, cfg.Port),
+		Handler:           h,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// shutdown - waits for requests in progress until timeout, then closes connections left
+func shutdown(server *http.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Println("requests are not finished in time, closing connections")
+		err = server.Close()
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// openStore - creates data store of type selected in config, its changes are published to broker
+func openStore(cfg config.Config, broker *stream.Broker) (service.Store, error) {
+	rules := service.Rules{RejectOverlaps: cfg.Calendar.RejectOverlaps, MaxEvents: cfg.Limits.MaxEventsPerUser}
+	opts := []storage.Option{storage.WithBroker(broker), storage.WithRules(rules)}
+
+	switch cfg.Storage.Type {
+	case "", "memory":
+		return storage.NewEventStorage(opts...), nil
+	case "file":
+		return storage.OpenFileStorage(cfg.Storage.Dir, cfg.Storage.SnapshotInterval, opts...)
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", cfg.Storage.Type)
 	}
 }
@@ -0,0 +1,34 @@
+package dev11
+
+import (
+	"main.go/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	data := "http_server:\n  ip: localhost\n  port: 8080\n  read_timeout: 3s\n  max_header_bytes: 4096\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.ReadConfigYaml(path)
+	if err != nil {
+		t.Fatalf("config is not read: %v", err)
+	}
+
+	server := cfg.HttpServer
+	if server.ReadTimeout != 3*time.Second || server.MaxHeaderBytes != 4096 {
+		t.Errorf("values from file are lost\nShould: 3s 4096\nGot: %v %d", server.ReadTimeout, server.MaxHeaderBytes)
+	}
+	if server.WriteTimeout == 0 || server.ReadHeaderTimeout == 0 || server.IdleTimeout == 0 || server.ShutdownTimeout == 0 {
+		t.Errorf("missing values should get defaults\nGot: %+v", server)
+	}
+	if limits := cfg.Limits; limits.RequestsPerSecond == 0 || limits.MaxBodyBytes == 0 || limits.MaxEventsPerUser == 0 {
+		t.Errorf("missing limits should get defaults\nGot: %+v", limits)
+	}
+}
@@ -0,0 +1,97 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"main.go/internal/service"
+	"main.go/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConflicts(t *testing.T) {
+	api := handler.NewHandlerWithStore(storage.NewEventStorage(storage.WithRules(service.Rules{RejectOverlaps: true})))
+
+	// event - returns event of first user in February 2022
+	event := func(id, day, startHour, endHour int) model.Event {
+		end := model.Date{Time: time.Date(2022, 2, day, endHour, 0, 0, 0, time.UTC)}
+		return model.Event{
+			EventID: id,
+			UserID:  1,
+			Date:    model.Date{Time: time.Date(2022, 2, day, startHour, 0, 0, 0, time.UTC)},
+			End:     &end,
+		}
+	}
+
+	// create - sends event and returns status code
+	create := func(event model.Event) int {
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBuffer(jsonEncode(event)))
+		w := httptest.NewRecorder()
+
+		api.CreateEvent(w, r)
+
+		return w.Code
+	}
+
+	if code := create(event(1, 1, 10, 12)); code != http.StatusOK {
+		t.Fatalf("event is not created: %d", code)
+	}
+
+	t.Run("reject on overlap", func(t *testing.T) {
+		// weekly event which meets the first one only on its second occurrence
+		weekly := event(6, 8, 9, 11)
+		weekly.Date.Time = weekly.Date.AddDate(0, 0, -14)
+		weekly.End.Time = weekly.End.AddDate(0, 0, -14)
+		weekly.Recurrence = &model.Recurrence{Freq: model.Weekly}
+
+		// single event years later and daily series which meets it
+		daily := event(7, 3, 20, 21)
+		daily.Recurrence = &model.Recurrence{Freq: model.Daily}
+		far := event(8, 3, 20, 22)
+		far.Date.Time = far.Date.AddDate(8, 0, 0)
+		far.End.Time = far.End.AddDate(8, 0, 0)
+
+		tests := []struct {
+			name  string
+			event model.Event
+			want  int
+		}{
+			{name: "overlapping", event: event(2, 1, 11, 13), want: http.StatusServiceUnavailable},
+			{name: "adjacent", event: event(3, 1, 12, 13), want: http.StatusOK},
+			{name: "end before start", event: event(4, 2, 12, 11), want: http.StatusBadRequest},
+			{name: "next day", event: event(5, 2, 10, 12), want: http.StatusOK},
+			{name: "recurring", event: weekly, want: http.StatusServiceUnavailable},
+			{name: "far event", event: far, want: http.StatusOK},
+			{name: "series meeting far event", event: daily, want: http.StatusServiceUnavailable},
+		}
+
+		for _, tt := range tests {
+			if code := create(tt.event); code != tt.want {
+				t.Errorf("%s: wrong status\nShould: %d\nGot: %d", tt.name, tt.want, code)
+			}
+		}
+	})
+
+	t.Run("conflicts endpoint", func(t *testing.T) {
+		r := httptest.NewRequest("GET",
+			"http://127.0.0.1:8080/conflicts?user_id=1&start=2022-02-01T11:30&end=2022-02-02T10:30&event_id=3", nil)
+		w := httptest.NewRecorder()
+
+		var responseEvent handler.ResultResponse
+
+		api.GetConflicts(w, r)
+
+		err := json.Unmarshal(w.Body.Bytes(), &responseEvent)
+		if err != nil {
+			t.Fatalf("cannot read response: %v", err)
+		}
+
+		if len(responseEvent.Result) != 2 || responseEvent.Result[0].EventID != 1 || responseEvent.Result[1].EventID != 5 {
+			t.Errorf("wrong conflicts\nShould: events 1 and 5\nGot: %v", responseEvent.Result)
+		}
+	})
+}
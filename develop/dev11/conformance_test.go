@@ -0,0 +1,506 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"main.go/internal/auth"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"main.go/internal/rpc"
+	"main.go/internal/service"
+	"main.go/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Outcomes of calls, both front-ends turn their statuses and codes into them
+const (
+	outcomeOK              = "ok"
+	outcomeInvalid         = "invalid"
+	outcomeForbidden       = "forbidden"
+	outcomeNotFound        = "not_found"
+	outcomeConflict        = "conflict"
+	outcomeVersionRequired = "version_required"
+	outcomeQuota           = "quota"
+	outcomeUnavailable     = "unavailable"
+)
+
+// frontEnd - transport of service seen by conformance suite, user is the acting one
+type frontEnd interface {
+	create(user int, event model.Event) (model.Event, string)
+	update(user int, event model.Event) (model.Event, string)
+	remove(user, userID, eventID, version int) (model.Event, string)
+	get(user, userID, eventID int) (model.Event, string)
+	day(user int, date string) ([]model.Event, string)
+	respond(user, ownerID, eventID int, status string) (model.Event, string)
+	invitations(user int) ([]model.Event, string)
+	search(user int, q string) ([]model.Event, string)
+	slot(user int, userIDs []int, from, to, duration string) (model.Interval, string)
+}
+
+// newServer - returns mux with both front-ends of one service, users 1, 2 and 3 have API keys
+// and every user may have two events
+func newServer() *http.ServeMux {
+	keys := map[string]int{"key-1": 1, "key-2": 2, "key-3": 3}
+	svc := service.New(storage.NewEventStorage(storage.WithRules(service.Rules{MaxEvents: 2})))
+	api := handler.NewHandlerWithService(svc, handler.WithAuth(auth.New("secret", keys)))
+
+	mux := http.NewServeMux()
+	api.Register(mux)
+	mux.Handle("POST /rpc", api.Authenticate(rpc.NewServer(svc)))
+
+	return mux
+}
+
+func TestConformance(t *testing.T) {
+	frontEnds := map[string]func(mux *http.ServeMux) frontEnd{
+		"http":    func(mux *http.ServeMux) frontEnd { return &httpFrontEnd{mux: mux} },
+		"jsonrpc": func(mux *http.ServeMux) frontEnd { return &rpcFrontEnd{mux: mux} },
+	}
+
+	for name, newFrontEnd := range frontEnds {
+		t.Run(name, func(t *testing.T) {
+			conformance(t, newFrontEnd(newServer()))
+		})
+	}
+}
+
+// conformance - runs the same calls against front-end, every one should give the same outcome
+func conformance(t *testing.T, f frontEnd) {
+	// expect - checks outcome of call
+	expect := func(step, want, got string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("%s: wrong outcome\nShould: %s\nGot: %s", step, want, got)
+		}
+	}
+
+	date := func(hour int) model.Date {
+		return model.Date{Time: time.Date(2022, 2, 1, hour, 0, 0, 0, time.UTC)}
+	}
+	end := date(11)
+
+	standup := model.Event{Title: "daily standup", Date: date(10), End: &end, Attendees: []model.Attendee{{UserID: 2}}}
+
+	created, outcome := f.create(1, standup)
+	expect("create", outcomeOK, outcome)
+	if created.UserID != 1 || created.EventID < 1 || created.Version != 1 {
+		t.Fatalf("created event should get acting user, id and version\nShould: user 1, version 1\nGot: %+v", created)
+	}
+
+	wrong := standup
+	early := date(9)
+	wrong.End = &early
+	_, outcome = f.create(1, wrong)
+	expect("create with end before start", outcomeInvalid, outcome)
+
+	foreign := standup
+	foreign.UserID = 1
+	_, outcome = f.create(2, foreign)
+	expect("create for another user", outcomeForbidden, outcome)
+
+	_, outcome = f.get(1, 1, created.EventID)
+	expect("get", outcomeOK, outcome)
+	_, outcome = f.get(2, 1, created.EventID)
+	expect("get event of another user", outcomeForbidden, outcome)
+	_, outcome = f.get(1, 1, 99)
+	expect("get missing event", outcomeNotFound, outcome)
+
+	changed := created
+	changed.Title = "weekly standup"
+	changed.Version = 0
+	_, outcome = f.update(1, changed)
+	expect("update without version", outcomeVersionRequired, outcome)
+
+	changed.Version = 1
+	updated, outcome := f.update(1, changed)
+	expect("update", outcomeOK, outcome)
+	if updated.Version != 2 || updated.Title != "weekly standup" {
+		t.Fatalf("update should give the new version\nShould: 2\nGot: %+v", updated)
+	}
+
+	_, outcome = f.update(1, changed)
+	expect("update of stale version", outcomeConflict, outcome)
+
+	events, outcome := f.day(1, "2022-02-01")
+	expect("events for day", outcomeOK, outcome)
+	if len(events) != 1 {
+		t.Fatalf("events for day\nShould: 1\nGot: %d", len(events))
+	}
+	_, outcome = f.day(1, "2022-02-31")
+	expect("events for wrong day", outcomeInvalid, outcome)
+
+	answered, outcome := f.respond(2, 1, created.EventID, model.Accepted)
+	expect("respond", outcomeOK, outcome)
+	if status, _ := answered.Attendee(2); status.Status != model.Accepted {
+		t.Fatalf("answer is not saved\nShould: %s\nGot: %+v", model.Accepted, answered.Attendees)
+	}
+	_, outcome = f.respond(2, 1, created.EventID, "maybe")
+	expect("respond with unknown status", outcomeInvalid, outcome)
+	_, outcome = f.respond(3, 1, created.EventID, model.Accepted)
+	expect("respond without invitation", outcomeNotFound, outcome)
+
+	events, outcome = f.invitations(2)
+	expect("invitations", outcomeOK, outcome)
+	if len(events) != 1 {
+		t.Fatalf("invitations\nShould: 1\nGot: %d", len(events))
+	}
+
+	events, outcome = f.search(1, "week")
+	expect("search", outcomeOK, outcome)
+	if len(events) != 1 {
+		t.Fatalf("search\nShould: 1\nGot: %d", len(events))
+	}
+	_, outcome = f.search(1, " ")
+	expect("search without words", outcomeInvalid, outcome)
+
+	// both users are busy 10:00 - 11:00, working day starts at 9:00
+	slot, outcome := f.slot(1, []int{1, 2}, "2022-02-01T09:00:00Z", "2022-02-01T13:00:00Z", "1h")
+	expect("suggest slot", outcomeOK, outcome)
+	if !slot.Start.Equal(date(9).Time) || !slot.End.Equal(date(10).Time) {
+		t.Fatalf("wrong slot\nShould: 09:00 - 10:00\nGot: %v - %v", slot.Start, slot.End)
+	}
+	_, outcome = f.slot(1, []int{1, 2}, "2022-02-01T09:00:00Z", "2022-02-01T13:00:00Z", "3h")
+	expect("suggest slot longer than free time", outcomeUnavailable, outcome)
+
+	lunch := model.Event{Title: "lunch", Date: date(13)}
+	_, outcome = f.create(1, lunch)
+	expect("create within quota", outcomeOK, outcome)
+	_, outcome = f.create(1, lunch)
+	expect("create over quota", outcomeQuota, outcome)
+
+	_, outcome = f.remove(1, 1, created.EventID, 0)
+	expect("delete without version", outcomeVersionRequired, outcome)
+	_, outcome = f.remove(1, 1, 99, 1)
+	expect("delete missing event", outcomeNotFound, outcome)
+	_, outcome = f.remove(1, 1, created.EventID, updated.Version)
+	expect("delete of version before answer", outcomeConflict, outcome)
+	_, outcome = f.remove(2, 1, created.EventID, answered.Version)
+	expect("delete event of another user", outcomeForbidden, outcome)
+
+	deleted, outcome := f.remove(1, 1, created.EventID, answered.Version)
+	expect("delete", outcomeOK, outcome)
+	if deleted.EventID != created.EventID {
+		t.Fatalf("deleted event is not returned\nShould: %d\nGot: %+v", created.EventID, deleted)
+	}
+
+	_, outcome = f.get(1, 1, created.EventID)
+	expect("get deleted event", outcomeNotFound, outcome)
+}
+
+// httpFrontEnd - calls REST and legacy routes of handler
+type httpFrontEnd struct {
+	mux *http.ServeMux
+}
+
+// do - sends request of user and decodes response into result, returns outcome of status
+func (f *httpFrontEnd) do(user int, method, target string, body []byte, header http.Header, result interface{}) string {
+	r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	r.Header.Set("X-API-Key", "key-"+strconv.Itoa(user))
+	w := httptest.NewRecorder()
+
+	f.mux.ServeHTTP(w, r)
+
+	if w.Code == http.StatusOK && result != nil {
+		_ = json.Unmarshal(w.Body.Bytes(), result)
+	}
+
+	switch w.Code {
+	case http.StatusOK:
+		return outcomeOK
+	case http.StatusBadRequest:
+		return outcomeInvalid
+	case http.StatusForbidden:
+		return outcomeForbidden
+	case http.StatusNotFound:
+		return outcomeNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return outcomeConflict
+	case http.StatusPreconditionRequired:
+		return outcomeVersionRequired
+	case http.StatusInsufficientStorage:
+		return outcomeQuota
+	case http.StatusServiceUnavailable:
+		return outcomeUnavailable
+	default:
+		return fmt.Sprintf("status %d: %s", w.Code, w.Body)
+	}
+}
+
+// event - does request which returns one event
+func (f *httpFrontEnd) event(user int, method, target string, body []byte, header http.Header) (model.Event, string) {
+	var response handler.ResultResponse
+	outcome := f.do(user, method, target, body, header, &response)
+	if outcome == outcomeOK && len(response.Result) == 1 {
+		return response.Result[0], outcome
+	}
+	return model.Event{}, outcome
+}
+
+// events - does request which returns list of events
+func (f *httpFrontEnd) events(user int, target string) ([]model.Event, string) {
+	var response handler.ResultResponse
+	outcome := f.do(user, http.MethodGet, target, nil, nil, &response)
+	return response.Result, outcome
+}
+
+func (f *httpFrontEnd) create(user int, event model.Event) (model.Event, string) {
+	return f.event(user, http.MethodPost, "/create_event", jsonEncode(event), nil)
+}
+
+func (f *httpFrontEnd) update(user int, event model.Event) (model.Event, string) {
+	target := fmt.Sprintf("/users/%d/events/%d", event.UserID, event.EventID)
+	return f.event(user, http.MethodPut, target, jsonEncode(event), nil)
+}
+
+func (f *httpFrontEnd) remove(user, userID, eventID, version int) (model.Event, string) {
+	header := http.Header{}
+	if version != 0 {
+		header.Set("If-Match", `"`+strconv.Itoa(version)+`"`)
+	}
+	return f.event(user, http.MethodDelete, fmt.Sprintf("/users/%d/events/%d", userID, eventID), nil, header)
+}
+
+func (f *httpFrontEnd) get(user, userID, eventID int) (model.Event, string) {
+	return f.event(user, http.MethodGet, fmt.Sprintf("/users/%d/events/%d", userID, eventID), nil, nil)
+}
+
+func (f *httpFrontEnd) day(user int, date string) ([]model.Event, string) {
+	return f.events(user, "/events_for_day?date="+url.QueryEscape(date))
+}
+
+func (f *httpFrontEnd) respond(user, ownerID, eventID int, status string) (model.Event, string) {
+	target := fmt.Sprintf("/users/%d/invitations/%d/%d", user, ownerID, eventID)
+	return f.event(user, http.MethodPut, target, []byte(`{"status": "`+status+`"}`), nil)
+}
+
+func (f *httpFrontEnd) invitations(user int) ([]model.Event, string) {
+	return f.events(user, "/invitations")
+}
+
+func (f *httpFrontEnd) search(user int, q string) ([]model.Event, string) {
+	return f.events(user, "/search?q="+url.QueryEscape(q))
+}
+
+func (f *httpFrontEnd) slot(user int, userIDs []int, from, to, duration string) (model.Interval, string) {
+	ids := ""
+	for i, uID := range userIDs {
+		if i > 0 {
+			ids += ","
+		}
+		ids += strconv.Itoa(uID)
+	}
+
+	query := url.Values{"user_ids": {ids}, "from": {from}, "to": {to}, "duration": {duration}}
+
+	var response handler.SlotResponse
+	outcome := f.do(user, http.MethodGet, "/suggest_slot?"+query.Encode(), nil, nil, &response)
+	return response.Result, outcome
+}
+
+// rpcFrontEnd - calls methods of JSON-RPC server
+type rpcFrontEnd struct {
+	mux *http.ServeMux
+	id  int
+}
+
+// call - calls method of user and decodes result, returns outcome of error code
+func (f *rpcFrontEnd) call(user int, method string, params, result interface{}) string {
+	f.id++
+
+	body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params, "id": f.id})
+
+	r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080/rpc", bytes.NewReader(body))
+	r.Header.Set("X-API-Key", "key-"+strconv.Itoa(user))
+	w := httptest.NewRecorder()
+
+	f.mux.ServeHTTP(w, r)
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpc.Error      `json:"error"`
+		ID     int             `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.ID != f.id {
+		return fmt.Sprintf("status %d: %s", w.Code, w.Body)
+	}
+
+	if response.Error == nil {
+		_ = json.Unmarshal(response.Result, result)
+		return outcomeOK
+	}
+
+	switch response.Error.Code {
+	case rpc.CodeInvalidParams:
+		return outcomeInvalid
+	case rpc.CodeForbidden:
+		return outcomeForbidden
+	case rpc.CodeNotFound:
+		return outcomeNotFound
+	case rpc.CodeConflict:
+		return outcomeConflict
+	case rpc.CodeVersionRequired:
+		return outcomeVersionRequired
+	case rpc.CodeQuota:
+		return outcomeQuota
+	case rpc.CodeUnavailable:
+		return outcomeUnavailable
+	default:
+		return response.Error.Error()
+	}
+}
+
+func (f *rpcFrontEnd) create(user int, event model.Event) (model.Event, string) {
+	var result model.Event
+	outcome := f.call(user, "createEvent", rpc.EventParams{Event: event}, &result)
+	return result, outcome
+}
+
+func (f *rpcFrontEnd) update(user int, event model.Event) (model.Event, string) {
+	var result model.Event
+	outcome := f.call(user, "updateEvent", rpc.EventParams{Event: event}, &result)
+	return result, outcome
+}
+
+func (f *rpcFrontEnd) remove(user, userID, eventID, version int) (model.Event, string) {
+	var result model.Event
+	outcome := f.call(user, "deleteEvent", rpc.EventRef{UserID: userID, EventID: eventID, Version: version}, &result)
+	return result, outcome
+}
+
+func (f *rpcFrontEnd) get(user, userID, eventID int) (model.Event, string) {
+	var result model.Event
+	outcome := f.call(user, "getEvent", rpc.EventRef{UserID: userID, EventID: eventID}, &result)
+	return result, outcome
+}
+
+func (f *rpcFrontEnd) day(user int, date string) ([]model.Event, string) {
+	var result []model.Event
+	outcome := f.call(user, "eventsForDay", rpc.DateParams{Date: date}, &result)
+	return result, outcome
+}
+
+func (f *rpcFrontEnd) respond(user, ownerID, eventID int, status string) (model.Event, string) {
+	var result model.Event
+	outcome := f.call(user, "respondEvent", rpc.AnswerParams{OwnerID: ownerID, EventID: eventID, Status: status}, &result)
+	return result, outcome
+}
+
+func (f *rpcFrontEnd) invitations(user int) ([]model.Event, string) {
+	var result []model.Event
+	outcome := f.call(user, "invitations", nil, &result)
+	return result, outcome
+}
+
+func (f *rpcFrontEnd) search(user int, q string) ([]model.Event, string) {
+	var result []model.Event
+	outcome := f.call(user, "search", rpc.SearchParams{Q: q}, &result)
+	return result, outcome
+}
+
+func (f *rpcFrontEnd) slot(user int, userIDs []int, from, to, duration string) (model.Interval, string) {
+	var result model.Interval
+	params := rpc.FreeBusyParams{UserIDs: userIDs, From: from, To: to, Duration: duration}
+	outcome := f.call(user, "suggestSlot", params, &result)
+	return result, outcome
+}
+
+func TestJSONRPCProtocol(t *testing.T) {
+	mux := newServer()
+
+	// post - sends body to /rpc as user 1 and returns recorder
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080/rpc", bytes.NewReader([]byte(body)))
+		r.Header.Set("X-API-Key", "key-1")
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// code - returns code of error of single response, zero for result
+	code := func(w *httptest.ResponseRecorder) int {
+		var response rpc.Response
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Error == nil {
+			return 0
+		}
+		return response.Error.Code
+	}
+
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "parse error", body: `{"jsonrpc": "2.0", "method": `, want: rpc.CodeParseError},
+		{name: "wrong version", body: `{"jsonrpc": "1.0", "method": "trash", "id": 1}`, want: rpc.CodeInvalidRequest},
+		{name: "object id", body: `{"jsonrpc": "2.0", "method": "trash", "id": {}}`, want: rpc.CodeInvalidRequest},
+		{name: "unknown method", body: `{"jsonrpc": "2.0", "method": "dropAll", "id": 1}`, want: rpc.CodeMethodNotFound},
+		{name: "unknown param", body: `{"jsonrpc": "2.0", "method": "trash", "params": {"user": 1}, "id": 1}`, want: rpc.CodeInvalidParams},
+		{name: "empty batch", body: `[]`, want: rpc.CodeInvalidRequest},
+		{name: "call", body: `{"jsonrpc": "2.0", "method": "trash", "id": "a"}`},
+	}
+
+	for _, c := range cases {
+		w := post(c.body)
+		if w.Code != http.StatusOK || code(w) != c.want {
+			t.Errorf("%s: wrong response\nShould: 200 code %d\nGot: %d %s", c.name, c.want, w.Code, w.Body)
+		}
+	}
+
+	// notification is executed without response
+	w := post(`{"jsonrpc": "2.0", "method": "createEvent", "params": {"event": {"title": "quiet", "date": "2022-02-01"}}}`)
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("notification should get no response\nShould: %d\nGot: %d %s", http.StatusNoContent, w.Code, w.Body)
+	}
+
+	w = post(`[
+		{"jsonrpc": "2.0", "method": "eventsForDay", "params": {"date": "2022-02-01"}, "id": 1},
+		{"jsonrpc": "2.0", "method": "trash"},
+		{"jsonrpc": "2.0", "method": "getEvent", "params": {"event_id": 7}, "id": 2}
+	]`)
+
+	var responses []rpc.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil || len(responses) != 2 {
+		t.Fatalf("batch should get response for every call except notification\nShould: 2\nGot: %s", w.Body)
+	}
+	if string(responses[0].ID) != "1" || responses[0].Error != nil {
+		t.Errorf("first call of batch\nShould: result of id 1\nGot: %s", w.Body)
+	}
+	if string(responses[1].ID) != "2" || responses[1].Error == nil || responses[1].Error.Code != rpc.CodeNotFound {
+		t.Errorf("second call of batch\nShould: code %d of id 2\nGot: %s", rpc.CodeNotFound, w.Body)
+	}
+
+	// fields of wrong event are in data of error
+	w = post(`{"jsonrpc": "2.0", "method": "createEvent", "params": {"event": {"date": "2022-02-01T10:00:00Z", "end": "2022-02-01T09:00:00Z"}}, "id": 3}`)
+	var response struct {
+		Error struct {
+			Code int `json:"code"`
+			Data struct {
+				Fields map[string]string `json:"fields"`
+			} `json:"data"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != rpc.CodeInvalidParams || response.Error.Data.Fields["end"] == "" {
+		t.Errorf("wrong event\nShould: code %d with field end\nGot: %s", rpc.CodeInvalidParams, w.Body)
+	}
+
+	// credentials are checked like in HTTP front-end
+	r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080/rpc", bytes.NewReader([]byte(`{"jsonrpc": "2.0", "method": "trash", "id": 1}`)))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("call without credentials\nShould: %d\nGot: %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,144 @@
+package dev11
+
+import (
+	"encoding/json"
+	"main.go/internal/handler"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestContentNegotiation(t *testing.T) {
+	api := handler.NewHandler()
+
+	// send - sends body with content type to handler
+	send := func(h http.HandlerFunc, contentType, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+
+		h(w, r)
+
+		return w
+	}
+
+	form := url.Values{}
+	form.Set("user_id", "3")
+	form.Set("date", "2019-09-09T10:00")
+	form.Set("end", "2019-09-09T11:00")
+	form.Set("tz", "Europe/Moscow")
+	form.Set("title", "form event")
+	form.Set("rrule", "FREQ=WEEKLY;COUNT=3")
+	form.Set("exceptions", "2019-09-16")
+
+	t.Run("form create", func(t *testing.T) {
+		w := send(api.CreateEvent, "application/x-www-form-urlencoded", form.Encode())
+
+		var responseEvent handler.ResultResponse
+
+		err := json.Unmarshal(w.Body.Bytes(), &responseEvent)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("event is not created: %s", w.Body.String())
+		}
+
+		event := responseEvent.Result[0]
+		if event.EventID != 1 || event.Title != "form event" || event.Date.UTC().Hour() != 7 ||
+			event.Recurrence == nil || event.Recurrence.Count != 3 || len(event.Exceptions) != 1 {
+			t.Errorf("wrong event: %+v", event)
+		}
+	})
+
+	t.Run("form update and delete", func(t *testing.T) {
+		form.Set("event_id", "1")
+		form.Set("title", "updated form event")
+		form.Set("version", "1")
+
+		w := send(api.UpdateEvent, "application/x-www-form-urlencoded; charset=utf-8", form.Encode())
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "updated form event") {
+			t.Errorf("event is not updated: %s", w.Body.String())
+		}
+
+		w = send(api.DeleteEvent, "application/x-www-form-urlencoded", "user_id=3&event_id=1&version=2")
+		if w.Code != http.StatusOK {
+			t.Errorf("event is not deleted: %s", w.Body.String())
+		}
+	})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		fields      []string
+	}{
+		{
+			name:        "json fields",
+			contentType: "application/json",
+			body:        `{"user_id": -1, "event_id": 1, "date": "09.09.2019", "tz": "Mars/Olympus"}`,
+			fields:      []string{"date", "tz"},
+		},
+		{
+			name:        "json rules",
+			contentType: "application/json",
+			body:        `{"user_id": -1, "event_id": 1, "date": "2019-09-09T10:00", "end": "2019-09-09T09:00"}`,
+			fields:      []string{"user_id", "end"},
+		},
+		{
+			name:        "form fields",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user_id=1&event_id=abc&date=tomorrow&rrule=FREQ=HOURLY",
+			fields:      []string{"event_id", "date", "rrule"},
+		},
+		{
+			name:        "json without date",
+			contentType: "application/json",
+			body:        `{"user_id": 1, "title": "no date"}`,
+			fields:      []string{"date"},
+		},
+		{
+			name:        "json empty date",
+			contentType: "application/json",
+			body:        `{"user_id": 1, "title": "empty date", "date": ""}`,
+			fields:      []string{"date"},
+		},
+		{
+			name:        "form without date",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user_id=1&title=no+date",
+			fields:      []string{"date"},
+		},
+		{
+			name:        "form empty date",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user_id=1&title=empty+date&date=",
+			fields:      []string{"date"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(api.CreateEvent, tt.contentType, tt.body)
+
+			var errEvent handler.ErrorResponse
+
+			err := json.Unmarshal(w.Body.Bytes(), &errEvent)
+			if err != nil || w.Code != http.StatusBadRequest {
+				t.Fatalf("wrong response: %d %s", w.Code, w.Body.String())
+			}
+
+			for _, field := range tt.fields {
+				if errEvent.Fields[field] == "" {
+					t.Errorf("no error for field %s: %v", field, errEvent.Fields)
+				}
+			}
+		})
+	}
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		w := send(api.CreateEvent, "text/plain", "user_id=1")
+		if w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("wrong status\nShould: %d\nGot: %d", http.StatusUnsupportedMediaType, w.Code)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package dev11
+
+import (
+	"main.go/internal/model"
+	"main.go/internal/storage"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFileStorage(t *testing.T) {
+	testData := readData("test_data/test_data")
+
+	t.Run("reopen after crash", func(t *testing.T) {
+		dir := t.TempDir()
+
+		store := openFileStorage(t, dir)
+		for i := range testData {
+			if err := store.CreateEvent(&testData[i]); err != nil {
+				t.Fatalf("cannot create event: %v", err)
+			}
+		}
+
+		updated := testData[0]
+		updated.Title = "updated after start"
+		if err := store.UpdateEvent(updated.UserID, updated.EventID, &updated); err != nil {
+			t.Fatalf("cannot update event: %v", err)
+		}
+		store.DeleteEvent(testData[1].UserID, testData[1].EventID, 0)
+
+		// store is dropped without Close, as if process was killed
+		reopened := openFileStorage(t, dir)
+		defer reopened.Close()
+
+		events := monthEvents(t, reopened, testData[0])
+		if len(events) != 5 {
+			t.Fatalf("wrong number of events after reopen\nShould: 5\nGot: %v", events)
+		}
+		if events[0].Title != updated.Title {
+			t.Errorf("update is lost\nShould: %s\nGot: %s", updated.Title, events[0].Title)
+		}
+		if events[1].EventID != testData[2].EventID {
+			t.Errorf("wrong event after reopen\nShould: %v\nGot: %v", testData[2], events[1])
+		}
+	})
+
+	t.Run("snapshot and wal", func(t *testing.T) {
+		dir := t.TempDir()
+
+		store := openFileStorage(t, dir)
+		for i := range testData[:3] {
+			if err := store.CreateEvent(&testData[i]); err != nil {
+				t.Fatalf("cannot create event: %v", err)
+			}
+		}
+		if err := store.Close(); err != nil {
+			t.Fatalf("cannot close storage: %v", err)
+		}
+
+		// changes after snapshot live only in wal
+		store = openFileStorage(t, dir)
+		store.DeleteEvent(testData[0].UserID, testData[0].EventID, 0)
+
+		reopened := openFileStorage(t, dir)
+		defer reopened.Close()
+
+		events := monthEvents(t, reopened, testData[0])
+		if len(events) != 2 || events[0].EventID != testData[1].EventID {
+			t.Errorf("wrong events after reopen\nShould: %v\nGot: %v", testData[1:3], events)
+		}
+	})
+
+	t.Run("ids are not reused after restart", func(t *testing.T) {
+		dir := t.TempDir()
+
+		store := openFileStorage(t, dir)
+		event := model.Event{UserID: 1, Date: testData[0].Date}
+		if err := store.CreateEvent(&event); err != nil {
+			t.Fatalf("cannot create event: %v", err)
+		}
+		store.DeleteEvent(event.UserID, event.EventID, 0)
+		if err := store.Close(); err != nil {
+			t.Fatalf("cannot close storage: %v", err)
+		}
+
+		reopened := openFileStorage(t, dir)
+		defer reopened.Close()
+
+		next := model.Event{UserID: 1, Date: testData[0].Date}
+		if err := reopened.CreateEvent(&next); err != nil {
+			t.Fatalf("cannot create event: %v", err)
+		}
+		if next.EventID != event.EventID+1 {
+			t.Errorf("wrong id after restart\nShould: %d\nGot: %d", event.EventID+1, next.EventID)
+		}
+	})
+
+	t.Run("torn wal record", func(t *testing.T) {
+		dir := t.TempDir()
+
+		store := openFileStorage(t, dir)
+		if err := store.CreateEvent(&testData[0]); err != nil {
+			t.Fatalf("cannot create event: %v", err)
+		}
+
+		// crash in the middle of writing next record
+		wal, err := os.OpenFile(filepath.Join(dir, "wal.log"), os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			t.Fatalf("cannot open wal: %v", err)
+		}
+		if _, err = wal.WriteString(`{"op":"put","event":{"event_id":2,"us`); err != nil {
+			t.Fatalf("cannot write wal: %v", err)
+		}
+		_ = wal.Close()
+
+		reopened := openFileStorage(t, dir)
+		if err = reopened.CreateEvent(&testData[1]); err != nil {
+			t.Fatalf("cannot create event after recovery: %v", err)
+		}
+
+		again := openFileStorage(t, dir)
+		defer again.Close()
+
+		events := monthEvents(t, again, testData[0])
+		if len(events) != 2 {
+			t.Errorf("wrong events after recovery\nShould: %v\nGot: %v", testData[:2], events)
+		}
+	})
+}
+
+// openFileStorage - opens file storage without periodic snapshots
+func openFileStorage(t *testing.T, dir string) *storage.FileStorage {
+	t.Helper()
+
+	store, err := storage.OpenFileStorage(dir, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %v", err)
+	}
+
+	return store
+}
+
+// monthEvents - returns events of month and user of event sorted by id
+func monthEvents(t *testing.T, store *storage.FileStorage, event model.Event) []model.Event {
+	t.Helper()
+
+	events, err := store.GetEventsForMonth(event.Date.Time, event.UserID)
+	if err != nil {
+		t.Fatalf("cannot get events: %v", err)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].EventID < events[j].EventID
+	})
+
+	return events
+}
@@ -0,0 +1,172 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"main.go/internal/service"
+	"main.go/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFreeBusy(t *testing.T) {
+	// user 2 works in Moscow from 10:00 to 17:00, that is 07:00 - 14:00 UTC
+	moscow, err := model.ParseWorkingHours("10:00", "17:00", nil, "Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	hours := service.WithWorkingHours(model.DefaultWorkingHours(), map[int]model.WorkingHours{2: moscow})
+	handler.NewHandlerWithService(service.New(storage.NewEventStorage(), hours)).Register(mux)
+
+	// serve - sends request through mux and returns recorder
+	serve := func(method, target string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// at - returns time of 2022-02-01, it is Tuesday
+	at := func(hour, minute int) time.Time {
+		return time.Date(2022, 2, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	// event - returns event of user from start to end
+	event := func(userID int, start, end time.Time, attendees ...model.Attendee) model.Event {
+		return model.Event{UserID: userID, Title: "meeting", Date: model.Date{Time: start}, End: &model.Date{Time: end}, Attendees: attendees}
+	}
+
+	noEnd := event(2, at(13, 0), at(13, 0))
+	noEnd.End = nil
+
+	events := []model.Event{
+		event(1, at(9, 0), at(10, 0)),
+		event(3, at(10, 0), at(11, 0), model.Attendee{UserID: 1}),
+		event(2, at(10, 30), at(12, 0)),
+		event(3, at(12, 0), at(13, 0), model.Attendee{UserID: 2}),
+		noEnd,
+	}
+	for _, e := range events {
+		if w := serve("POST", "/create_event", jsonEncode(e)); w.Code != http.StatusOK {
+			t.Fatalf("event is not created\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body)
+		}
+	}
+
+	// declined invitation does not take time
+	if w := serve("PUT", "/users/2/invitations/3/2", []byte(`{"status": "declined"}`)); w.Code != http.StatusOK {
+		t.Fatalf("answer is not saved\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	w := serve("GET", "/freebusy?user_ids=1,2&from=2022-02-01T00:00:00Z&to=2022-02-02T00:00:00Z", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("free/busy failed\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	var freeBusy handler.FreeBusyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &freeBusy); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []handler.FreeBusy{
+		{UserID: 1, Busy: []model.Interval{{Start: at(9, 0), End: at(11, 0)}}},
+		{UserID: 2, Busy: []model.Interval{{Start: at(10, 30), End: at(12, 0)}}},
+	}
+	if !sameFreeBusy(freeBusy.Result, want) {
+		t.Errorf("wrong busy intervals\nShould: %v\nGot: %v", want, freeBusy.Result)
+	}
+
+	var free handler.FreeBusyResponse
+	w = serve("GET", "/freebusy?user_ids=4&from=2022-02-01&to=2022-02-02", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &free); err != nil || len(free.Result) != 1 || free.Result[0].Busy == nil {
+		t.Errorf("user without events should have empty list of busy intervals\nShould: []\nGot: %s", w.Body)
+	}
+
+	// slot - returns suggested slot or status of failure
+	slot := func(query string) (int, model.Interval) {
+		w := serve("GET", "/suggest_slot?"+query, nil)
+
+		var response handler.SlotResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Result
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+		slot  model.Interval
+	}{
+		{
+			name:  "first common gap",
+			query: "user_ids=1,2&duration=1h&from=2022-02-01T00:00:00Z&to=2022-02-05T00:00:00Z",
+			code:  http.StatusOK,
+			slot:  model.Interval{Start: at(12, 0), End: at(13, 0)},
+		},
+		{
+			name:  "working hours of user 2 end at 14:00 UTC, slot moves to the next day",
+			query: "user_ids=1,2&duration=2h30m&from=2022-02-01T00:00:00Z&to=2022-02-05T00:00:00Z",
+			code:  http.StatusOK,
+			slot:  model.Interval{Start: at(9, 0).AddDate(0, 0, 1), End: at(11, 30).AddDate(0, 0, 1)},
+		},
+		{
+			name:  "weekend",
+			query: "user_ids=1&duration=1h&from=2022-02-05T00:00:00Z&to=2022-02-07T00:00:00Z",
+			code:  http.StatusServiceUnavailable,
+		},
+		{
+			name:  "wrong users",
+			query: "user_ids=1,x&duration=1h&from=2022-02-01T00:00:00Z&to=2022-02-02T00:00:00Z",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name:  "no duration",
+			query: "user_ids=1&from=2022-02-01T00:00:00Z&to=2022-02-02T00:00:00Z",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name:  "too long period",
+			query: "user_ids=1&duration=1h&from=2022-01-01T00:00:00Z&to=2023-01-01T00:00:00Z",
+			code:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, got := slot(tt.query)
+			if code != tt.code {
+				t.Fatalf("wrong status\nShould: %d\nGot: %d", tt.code, code)
+			}
+			if code == http.StatusOK && (!got.Start.Equal(tt.slot.Start) || !got.End.Equal(tt.slot.End)) {
+				t.Errorf("wrong slot\nShould: %v\nGot: %v", tt.slot, got)
+			}
+		})
+	}
+}
+
+// sameFreeBusy - compares busy intervals of users ignoring time zones
+func sameFreeBusy(a, b []handler.FreeBusy) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].UserID != b[i].UserID || len(a[i].Busy) != len(b[i].Busy) {
+			return false
+		}
+		for j := range a[i].Busy {
+			if !a[i].Busy[j].Start.Equal(b[i].Busy[j].Start) || !a[i].Busy[j].End.Equal(b[i].Busy[j].End) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
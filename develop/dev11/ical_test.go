@@ -0,0 +1,238 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/handler"
+	"main.go/internal/ical"
+	"main.go/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICalendar(t *testing.T) {
+	api := handler.NewHandler()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("cannot load time zone: %v", err)
+	}
+
+	end := model.Date{Time: time.Date(2022, 2, 1, 11, 0, 0, 0, moscow)}
+	events := []model.Event{
+		{
+			EventID: 1,
+			UserID:  1,
+			Title:   "Budget; review, Q1",
+			Descr:   "Обсуждение бюджета\nСтрока с очень длинным описанием, которое не помещается в одну строку календаря",
+			Date:    model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, moscow)},
+			TZ:      "Europe/Moscow",
+			End:     &end,
+			Recurrence: &model.Recurrence{
+				Freq:  model.Weekly,
+				Until: &model.Date{Time: time.Date(2022, 3, 1, 7, 0, 0, 0, time.UTC)},
+				ByDay: []string{"TU", "TH"},
+			},
+			Exceptions: []model.Date{{Time: time.Date(2022, 2, 3, 10, 0, 0, 0, moscow)}},
+		},
+		{
+			EventID: 2,
+			UserID:  1,
+			Title:   "single",
+			Date:    model.Date{Time: time.Date(2022, 2, 5, 12, 30, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, event := range events {
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBuffer(jsonEncode(event)))
+		w := httptest.NewRecorder()
+
+		api.CreateEvent(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("event is not created: %s", w.Body.String())
+		}
+	}
+
+	// export - returns calendar of user
+	export := func(userID string) string {
+		r := httptest.NewRequest("GET", "http://127.0.0.1:8080/calendar.ics?user_id="+userID, nil)
+		w := httptest.NewRecorder()
+
+		api.ExportICS(w, r)
+
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") {
+			t.Fatalf("calendar is not exported: %d %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+
+	exported := export("1")
+
+	t.Run("folded lines", func(t *testing.T) {
+		for _, line := range strings.Split(exported, "\r\n") {
+			if len(line) > 75 {
+				t.Errorf("line is longer than 75 octets: %q", line)
+			}
+		}
+	})
+
+	t.Run("time zone definitions", func(t *testing.T) {
+		if !strings.Contains(exported, "BEGIN:VTIMEZONE\r\nTZID:Europe/Moscow\r\n") {
+			t.Errorf("no VTIMEZONE for TZID of event: %s", exported)
+		}
+
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		if err != nil {
+			t.Fatalf("cannot load time zone: %v", err)
+		}
+
+		var b strings.Builder
+		err = ical.Encode(&b, []model.Event{{Date: model.Date{Time: time.Date(2022, 5, 1, 10, 0, 0, 0, berlin)}, TZ: "Europe/Berlin"}})
+		if err != nil {
+			t.Fatalf("cannot encode calendar: %v", err)
+		}
+
+		// summer time of 2022 starts on 27 March at 02:00 and ends on 30 October at 03:00 local time
+		for _, observance := range []string{
+			"BEGIN:STANDARD\r\nDTSTART:20220101T000000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD",
+			"BEGIN:DAYLIGHT\r\nDTSTART:20220327T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\nEND:DAYLIGHT",
+			"BEGIN:STANDARD\r\nDTSTART:20221030T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD",
+		} {
+			if !strings.Contains(b.String(), observance) {
+				t.Errorf("no observance in VTIMEZONE\nShould: %q\nGot: %s", observance, b.String())
+			}
+		}
+
+		decoded, _, err := ical.Decode(strings.NewReader(b.String()))
+		if err != nil || len(decoded) != 1 || decoded[0].TZ != "Europe/Berlin" {
+			t.Errorf("calendar with VTIMEZONE is not decoded: %v %v", decoded, err)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/import_ics?user_id=2", strings.NewReader(exported))
+		r.Header.Set("Content-Type", "text/calendar")
+		w := httptest.NewRecorder()
+
+		var responseEvent handler.ResultResponse
+
+		api.ImportICS(w, r)
+
+		err := json.Unmarshal(w.Body.Bytes(), &responseEvent)
+		if err != nil || len(responseEvent.Result) != len(events) {
+			t.Fatalf("calendar is not imported: %s", w.Body.String())
+		}
+
+		first, _, err := ical.Decode(strings.NewReader(exported))
+		if err != nil {
+			t.Fatalf("cannot decode calendar: %v", err)
+		}
+		second, _, err := ical.Decode(strings.NewReader(export("2")))
+		if err != nil {
+			t.Fatalf("cannot decode calendar: %v", err)
+		}
+
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("calendar changed after import\nShould: %v\nGot: %v", first, second)
+		}
+
+		if first[0].Descr != events[0].Descr || first[0].Title != events[0].Title || first[0].TZ != events[0].TZ {
+			t.Errorf("text or time zone changed\nShould: %v\nGot: %v", events[0], first[0])
+		}
+	})
+
+	t.Run("foreign calendar", func(t *testing.T) {
+		calendar := strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"PRODID:-//Mozilla.org/NONSGML Mozilla Calendar V1.1//EN",
+			"BEGIN:VEVENT",
+			"UID:some-uid@example.com",
+			"DTSTART;TZID=Europe/Berlin:20220321T100000",
+			"DURATION:PT1H30M",
+			"SUMMARY:Planning with a very long title which is folded by the client into",
+			"  two lines",
+			"RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=2",
+			"BEGIN:VALARM",
+			"TRIGGER:-PT15M",
+			"ACTION:DISPLAY",
+			"END:VALARM",
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\r\n")
+
+		decoded, skipped, err := ical.Decode(strings.NewReader(calendar))
+		if err != nil {
+			t.Fatalf("cannot decode calendar: %v", err)
+		}
+
+		if len(decoded) != 1 || len(skipped) != 0 {
+			t.Fatalf("wrong number of events: %v", decoded)
+		}
+
+		event := decoded[0]
+		if event.TZ != "Europe/Berlin" || event.Duration() != 90*time.Minute ||
+			event.Title != "Planning with a very long title which is folded by the client into two lines" {
+			t.Errorf("wrong event: %+v", event)
+		}
+
+		occurrences := event.Occurrences(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+		if len(occurrences) != 2 || occurrences[0].Day() != 25 || occurrences[1].Day() != 29 {
+			t.Errorf("wrong occurrences: %v", occurrences)
+		}
+	})
+
+	t.Run("unsupported rule", func(t *testing.T) {
+		calendar := strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"BEGIN:VEVENT",
+			"UID:yearly@example.com",
+			"SUMMARY:New year",
+			"DTSTART:20220101T100000Z",
+			"RRULE:FREQ=YEARLY;BYMONTH=1",
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:weekly@example.com",
+			"SUMMARY:Weekly",
+			"DTSTART:20220103T100000Z",
+			"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;WKST=SU",
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:biweekly@example.com",
+			"DTSTART:20220103T100000Z",
+			"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU;WKST=SU",
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\r\n")
+
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/import_ics?user_id=3", strings.NewReader(calendar))
+		w := httptest.NewRecorder()
+
+		api.ImportICS(w, r)
+
+		var response handler.ImportResponse
+
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != http.StatusOK || err != nil {
+			t.Fatalf("calendar is not imported: %d %s", w.Code, w.Body.String())
+		}
+
+		// WKST does not change weeks repeated every week, so only the other events are skipped
+		if len(response.Result) != 1 || response.Result[0].Title != "Weekly" {
+			t.Errorf("wrong imported events\nShould: %v\nGot: %v", "Weekly", response.Result)
+		}
+
+		skipped := make([]string, 0, len(response.Skipped))
+		for _, event := range response.Skipped {
+			skipped = append(skipped, event.UID)
+		}
+		if want := []string{"yearly@example.com", "biweekly@example.com"}; !reflect.DeepEqual(skipped, want) {
+			t.Errorf("wrong skipped events\nShould: %v\nGot: %v", want, response.Skipped)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenVersion - prefix of tokens signed by this package
+const tokenVersion = "v1"
+
+var (
+	// ErrInvalidToken - token is not signed by server or is damaged
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken - token is signed by server but its time is over
+	ErrExpiredToken = errors.New("token is expired")
+)
+
+// Authenticator - checks HMAC signed tokens and static API keys
+type Authenticator struct {
+	secret []byte
+	// keys - user of every API key
+	keys map[string]int
+}
+
+// New - creates authenticator, empty secret disables tokens and empty keys disable API keys
+func New(secret string, keys map[string]int) *Authenticator {
+	return &Authenticator{secret: []byte(secret), keys: keys}
+}
+
+// Enabled - checks if any credentials are configured, otherwise requests are not authenticated
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (len(a.secret) > 0 || len(a.keys) > 0)
+}
+
+// Issue - returns token of user valid for ttl
+func (a *Authenticator) Issue(userID int, ttl time.Duration) (string, error) {
+	if len(a.secret) == 0 {
+		return "", errors.New("tokens are disabled, secret is not set")
+	}
+
+	payload := fmt.Sprintf("%s.%d.%d", tokenVersion, userID, time.Now().Add(ttl).Unix())
+	return payload + "." + a.sign(payload), nil
+}
+
+// Verify - returns user of token or API key
+func (a *Authenticator) Verify(credential string) (int, error) {
+	if userID, ok := a.lookupKey(credential); ok {
+		return userID, nil
+	}
+
+	if len(a.secret) == 0 {
+		return 0, ErrInvalidToken
+	}
+
+	cut := strings.LastIndexByte(credential, '.')
+	if cut < 0 {
+		return 0, ErrInvalidToken
+	}
+
+	payload, signature := credential[:cut], credential[cut+1:]
+	if !hmac.Equal([]byte(signature), []byte(a.sign(payload))) {
+		return 0, ErrInvalidToken
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 || parts[0] != tokenVersion {
+		return 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(parts[1])
+	if err != nil || userID < 1 {
+		return 0, ErrInvalidToken
+	}
+
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	if time.Now().Unix() >= expires {
+		return 0, ErrExpiredToken
+	}
+
+	return userID, nil
+}
+
+// lookupKey - finds user of API key comparing every key in constant time
+func (a *Authenticator) lookupKey(credential string) (int, bool) {
+	var (
+		userID int
+		found  bool
+	)
+
+	for key, id := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(credential)) == 1 {
+			userID, found = id, true
+		}
+	}
+
+	return userID, found
+}
+
+// sign - returns HMAC-SHA256 of payload
+func (a *Authenticator) sign(payload string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
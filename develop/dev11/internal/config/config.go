@@ -4,18 +4,133 @@ import (
 	"main.go/internal/config/helper"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-// HttpServer - contains ip and port for http server
+// HttpServer - contains ip and port for http server and limits of connections
 type HttpServer struct {
 	IP   string `yaml:"ip"`
 	Port string `yaml:"port"`
+	// ReadHeaderTimeout - time to read request headers
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	// ReadTimeout - time to read the whole request with body
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+	// WriteTimeout - time from the end of request headers to the end of response
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// IdleTimeout - time to wait the next request on keep-alive connection
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// MaxHeaderBytes - limit of request headers size
+	MaxHeaderBytes int `yaml:"max_header_bytes"`
+	// ShutdownTimeout - time to finish requests in progress after stop signal
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// defaultHttpServer - limits used when config does not set them
+func defaultHttpServer() HttpServer {
+	return HttpServer{
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		MaxHeaderBytes:    1 << 20,
+		ShutdownTimeout:   15 * time.Second,
+	}
+}
+
+// Storage - contains data store settings
+type Storage struct {
+	// Type - memory or file
+	Type             string        `yaml:"type"`
+	Dir              string        `yaml:"dir"`
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"`
+}
+
+// Calendar - contains business rules of calendar
+type Calendar struct {
+	// RejectOverlaps - forbids events which overlap other events of the same user,
+	// two recurring events are compared only for two years from the later start
+	RejectOverlaps bool `yaml:"reject_overlaps"`
+	// StreamHistory - number of changes kept for clients of event stream reconnecting with Last-Event-ID
+	StreamHistory int `yaml:"stream_history"`
+	// DefaultWorkingHours - working hours of users not listed in WorkingHours, Monday to Friday 09:00 - 18:00 UTC if not set
+	DefaultWorkingHours WorkingHours `yaml:"default_working_hours"`
+	// WorkingHours - working hours by user id, slots for meetings are suggested only inside them
+	WorkingHours map[int]WorkingHours `yaml:"working_hours"`
+}
+
+// WorkingHours - daily time when user can meet
+type WorkingHours struct {
+	// Start, End - wall clock like 09:00, end of day is 24:00
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	// Days - mon, tue, wed, thu, fri, sat or sun, Monday to Friday if empty
+	Days []string `yaml:"days"`
+	// TZ - IANA time zone of wall clock, UTC if empty
+	TZ string `yaml:"tz"`
+}
+
+// Limits - protect server from clients sending too many or too big requests
+type Limits struct {
+	// RequestsPerSecond - rate of requests of one client, zero turns rate limiting off
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	// Burst - number of requests client can send at once above its rate
+	Burst int `yaml:"burst"`
+	// MaxBodyBytes - limit of request body, calendar import may be bigger
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// MaxEventsPerUser - quota of events of one user, zero means no quota
+	MaxEventsPerUser int `yaml:"max_events_per_user"`
+	// IdempotencyKeys - how many responses of requests with Idempotency-Key are remembered,
+	// they are kept in memory and forgotten on restart
+	IdempotencyKeys int `yaml:"idempotency_keys"`
+}
+
+// defaultLimits - limits used when config does not set them
+func defaultLimits() Limits {
+	return Limits{
+		RequestsPerSecond: 20,
+		Burst:             40,
+		MaxBodyBytes:      1 << 20,
+		MaxEventsPerUser:  10000,
+		IdempotencyKeys:   10000,
+	}
+}
+
+// Log - contains logger settings
+type Log struct {
+	// Level - debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format - json or text
+	Format string `yaml:"format"`
+}
+
+// Auth - contains credentials of clients, requests are not authenticated when nothing is set
+type Auth struct {
+	// Secret - key of HMAC signed bearer tokens
+	Secret string `yaml:"secret"`
+	// APIKeys - user of every static API key
+	APIKeys map[string]int `yaml:"api_keys"`
+}
+
+// Reminders - contains settings of reminder delivery, reminders are not sent without webhook
+type Reminders struct {
+	// WebhookURL - reminders are POSTed to it as json
+	WebhookURL string `yaml:"webhook_url"`
+	// Interval - how often due reminders are checked
+	Interval time.Duration `yaml:"interval"`
+	// StateFile - file with queued reminders and time of the last check
+	StateFile string `yaml:"state_file"`
 }
 
 type Config struct {
 	HttpServer HttpServer `yaml:"http_server"`
+	Log        Log        `yaml:"log"`
+	Storage    Storage    `yaml:"storage"`
+	Calendar   Calendar   `yaml:"calendar"`
+	Auth       Auth       `yaml:"auth"`
+	Reminders  Reminders  `yaml:"reminders"`
+	Limits     Limits     `yaml:"limits"`
 }
 
 func ReadConfigYaml(filePath string) (cfg Config, err error) {
@@ -25,6 +140,10 @@ func ReadConfigYaml(filePath string) (cfg Config, err error) {
 	}
 	defer helper.Closer(file)
 
+	// fields missing in file keep default values
+	cfg.HttpServer = defaultHttpServer()
+	cfg.Limits = defaultLimits()
+
 	decoder := yaml.NewDecoder(file)
 	err = decoder.Decode(&cfg)
 	if err != nil {
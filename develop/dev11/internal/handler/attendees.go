@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"main.go/internal/model"
+	"net/http"
+	"strconv"
+)
+
+// Answer - answer of attendee to invitation
+type Answer struct {
+	// UserID - attendee
+	UserID int `json:"user_id"`
+	// OwnerID - owner of event
+	OwnerID int    `json:"owner_id"`
+	EventID int    `json:"event_id"`
+	Status  string `json:"status"`
+}
+
+// RespondEvent - saves answer of attendee: accepted, declined, tentative or needs-action.
+// Answer is given in body or in path /users/{id}/invitations/{ownerID}/{eventID} with status in body
+func (h *Handler) RespondEvent(w http.ResponseWriter, r *http.Request) {
+	answer, err := h.decodeAnswer(r)
+	if err != nil {
+		h.decodeErrorResponse(w, err)
+		return
+	}
+
+	event, err := h.eventService.RespondEvent(r.Context(), answer.UserID, answer.OwnerID, answer.EventID, answer.Status)
+	if err != nil {
+		h.storeErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(event.Version))
+	h.resultResponse(w, []model.Event{event})
+}
+
+// GetInvitations - returns events user is invited to with answers of all attendees
+func (h *Handler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	events, err := h.eventService.GetInvitations(r.Context(), uID)
+	if err != nil {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	h.resultResponse(w, events)
+}
+
+// decodeAnswer - decodes answer from json or form body, ids from path take place of missing ones.
+// Rules of answer are checked by service
+func (h *Handler) decodeAnswer(r *http.Request) (*Answer, error) {
+	mediaType, err := bodyType(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		answer Answer
+		verr   model.ValidationError
+	)
+
+	if mediaType == contentTypeJSON {
+		err = json.NewDecoder(r.Body).Decode(&answer)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = r.ParseForm()
+		if err != nil {
+			return nil, err
+		}
+
+		// intField - parses integer field, missing field is zero
+		intField := func(name string) int {
+			value := r.PostForm.Get(name)
+			if value == "" {
+				return 0
+			}
+
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				verr.Add(name, fmt.Sprintf("%q is not integer", value))
+			}
+			return n
+		}
+
+		answer.UserID = intField("user_id")
+		answer.OwnerID = intField("owner_id")
+		answer.EventID = intField("event_id")
+		answer.Status = r.PostForm.Get("status")
+	}
+
+	// apply - takes id from path segment if it is there
+	apply := func(segment, field string, id *int) {
+		value := r.PathValue(segment)
+		if value == "" {
+			return
+		}
+
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			verr.Add(field, fmt.Sprintf("%q in path is not integer", value))
+		case *id != 0 && *id != n:
+			verr.Add(field, fmt.Sprintf("%d does not match %d in path", *id, n))
+		default:
+			*id = n
+		}
+	}
+
+	apply("id", "user_id", &answer.UserID)
+	apply("ownerID", "owner_id", &answer.OwnerID)
+	apply("eventID", "event_id", &answer.EventID)
+
+	logUser(r, answer.UserID)
+
+	if err = verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return &answer, nil
+}
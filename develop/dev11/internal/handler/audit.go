@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"main.go/internal/model"
+	"net/http"
+)
+
+// HistoryResponse - changes of event from the oldest one
+type HistoryResponse struct {
+	Result []model.AuditRecord `json:"result"`
+}
+
+// GetHistory - returns who, when and how changed event, history of deleted event is kept.
+// User is taken from path or user_id parameter
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	uID, eventID, err := h.eventPath(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	records, err := h.eventService.GetHistory(r.Context(), uID, eventID)
+	if err != nil {
+		h.storeErrorResponse(w, err)
+		return
+	}
+
+	h.jsonResponse(w, &HistoryResponse{Result: records})
+}
+
+// RestoreEvent - moves deleted event back from trash and returns it with new version
+func (h *Handler) RestoreEvent(w http.ResponseWriter, r *http.Request) {
+	uID, eventID, err := h.eventPath(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	event, err := h.eventService.RestoreEvent(r.Context(), uID, eventID)
+	if err != nil {
+		h.storeErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(event.Version))
+	h.resultResponse(w, []model.Event{event})
+}
+
+// GetTrash - returns deleted events of user which can be restored
+func (h *Handler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	events, err := h.eventService.GetTrash(r.Context(), uID)
+	if err != nil {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	h.resultResponse(w, events)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"main.go/internal/auth"
+	"main.go/internal/service"
+	"net/http"
+	"strings"
+)
+
+// apiKeyHeader - header with API key, token is sent in Authorization header
+const apiKeyHeader = "X-API-Key"
+
+// errForbidden - authenticated user asks for events of another user
+var errForbidden = service.ErrForbidden
+
+// WithAuth - requires token or API key in every request, acting user is taken from it.
+// Authenticator without credentials leaves requests unauthenticated
+func WithAuth(a *auth.Authenticator) Option {
+	return func(h *Handler) {
+		if a.Enabled() {
+			h.auth = a
+		}
+	}
+}
+
+// Authenticate - checks credentials of request and puts acting user into its context.
+// Other front-ends of service use it too, so they get the same acting user
+func (h *Handler) Authenticate(next http.Handler) http.Handler {
+	if h.auth == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credential := r.Header.Get(apiKeyHeader)
+		if credential == "" {
+			scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if strings.EqualFold(scheme, "Bearer") {
+				credential = strings.TrimSpace(token)
+			}
+		}
+
+		if credential == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			h.errorResponse(w, errors.New("authentication is required"), http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := h.auth.Verify(credential)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			h.errorResponse(w, err, http.StatusUnauthorized)
+			return
+		}
+
+		logUser(r, userID)
+		next.ServeHTTP(w, r.WithContext(service.WithActor(r.Context(), userID)))
+	})
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"main.go/internal/model"
+	"main.go/internal/service"
+	"net/http"
+)
+
+// BatchRequest - body of /batch request
+type BatchRequest struct {
+	// Atomic - all operations are applied or none, otherwise every operation is applied alone
+	Atomic     bool              `json:"atomic"`
+	Operations []model.Operation `json:"operations"`
+}
+
+// BatchResult - result of single operation of batch
+type BatchResult struct {
+	Status int          `json:"status"`
+	Event  *model.Event `json:"event,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	// Fields - problems of input fields, key is field name
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// BatchResponse - results of batch in order of operations
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// Batch - applies array of create, update and delete operations in one transaction of data store.
+// Atomic batch is applied entirely or not at all, failed operation gives status of response
+// and other operations get 424. Otherwise every operation is applied alone and response is 200
+func (h *Handler) Batch(w http.ResponseWriter, r *http.Request) {
+	mediaType, err := bodyType(r)
+	if err == nil && mediaType != contentTypeJSON {
+		err = errors.New("batch should be application/json")
+	}
+	if err != nil {
+		h.errorResponse(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var request BatchRequest
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		h.errorResponse(w, fmt.Errorf("error while decoding input value: %w", err), bodyErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	applied, err := h.eventService.Batch(r.Context(), request.Operations, request.Atomic)
+
+	var batchErr *service.BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	results := make([]BatchResult, len(applied))
+	for i, result := range applied {
+		results[i] = batchResult(result)
+	}
+
+	if batchErr != nil {
+		h.batchResponse(w, results, results[batchErr.Index].Status)
+		return
+	}
+
+	h.batchResponse(w, results, http.StatusOK)
+}
+
+// batchResult - converts result of data store to response, error gets status of the same error of single request
+func batchResult(result model.OperationResult) BatchResult {
+	if result.Err == nil {
+		event := result.Event
+		return BatchResult{Status: http.StatusOK, Event: &event}
+	}
+
+	response := BatchResult{Error: result.Err.Error()}
+
+	var verr *model.ValidationError
+	if errors.As(result.Err, &verr) {
+		response.Fields = verr.Fields
+	}
+
+	switch service.KindOf(result.Err) {
+	case service.KindNotApplied:
+		response.Status = http.StatusFailedDependency
+	case service.KindInvalid:
+		response.Status = http.StatusBadRequest
+	case service.KindForbidden:
+		response.Status = http.StatusForbidden
+	case service.KindVersionRequired:
+		response.Status = http.StatusPreconditionRequired
+	case service.KindNotFound:
+		response.Status = http.StatusNotFound
+	case service.KindConflict:
+		response.Status = http.StatusConflict
+	case service.KindQuota:
+		response.Status = http.StatusInsufficientStorage
+	default:
+		response.Status = http.StatusServiceUnavailable
+	}
+
+	return response
+}
+
+// batchResponse - writes results of batch with status
+func (h *Handler) batchResponse(w http.ResponseWriter, results []BatchResult, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	body, _ := json.MarshalIndent(&BatchResponse{Results: results}, " ", "")
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"main.go/internal/ical"
+	"main.go/internal/model"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// errUnsupportedMediaType - body is neither json nor form
+var errUnsupportedMediaType = errors.New("body should be application/json or application/x-www-form-urlencoded")
+
+// decodeEvent - decodes event from json or form body according to Content-Type, ids from REST path
+// are applied to event. Wrong fields are returned as *model.ValidationError, rules of event are checked by service
+func (h *Handler) decodeEvent(r *http.Request) (*model.Event, error) {
+	mediaType, err := bodyType(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var event *model.Event
+
+	switch mediaType {
+	case contentTypeJSON:
+		event, err = h.decodeJSON(r)
+	case contentTypeForm:
+		event, err = h.decodeForm(r)
+	default:
+		return nil, errUnsupportedMediaType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.applyPath(r, event)
+	if err != nil {
+		return nil, err
+	}
+
+	logUser(r, event.UserID)
+
+	return event, nil
+}
+
+// bodyType - returns media type of request body, body without Content-Type is json
+func bodyType(r *http.Request) (string, error) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return contentTypeJSON, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil || (mediaType != contentTypeJSON && mediaType != contentTypeForm) {
+		return "", errUnsupportedMediaType
+	}
+
+	return mediaType, nil
+}
+
+// decodeJSON - decode json format from request and returns event.
+// If event can not be decoded every field is decoded alone to find the wrong ones
+func (h *Handler) decodeJSON(r *http.Request) (*model.Event, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var event model.Event
+
+	err = json.Unmarshal(body, &event)
+	if err == nil {
+		return &event, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return nil, err
+	}
+
+	var verr model.ValidationError
+
+	for name, value := range fields {
+		single, _ := json.Marshal(map[string]json.RawMessage{name: value})
+
+		var probe model.Event
+		if fieldErr := json.Unmarshal(single, &probe); fieldErr != nil {
+			verr.Add(name, fieldErr.Error())
+		}
+	}
+
+	// problem is in combination of fields
+	if verr.Err() == nil {
+		return nil, err
+	}
+
+	return nil, &verr
+}
+
+// decodeForm - decode www-url-form-encoded body and returns event.
+// Recurrence is given as RFC 5545 RRULE, exceptions, attendee ids and minutes of reminders
+// are comma separated or repeated
+func (h *Handler) decodeForm(r *http.Request) (*model.Event, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return nil, err
+	}
+
+	form := r.PostForm
+
+	var (
+		event model.Event
+		verr  model.ValidationError
+	)
+
+	// intField - parses integer field, missing field is zero
+	intField := func(name string) int {
+		value := form.Get(name)
+		if value == "" {
+			return 0
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			verr.Add(name, fmt.Sprintf("%q is not integer", value))
+		}
+		return n
+	}
+
+	event.UserID = intField("user_id")
+	event.EventID = intField("event_id")
+	event.Version = intField("version")
+	event.Title = form.Get("title")
+	event.Descr = form.Get("descr")
+	event.TZ = form.Get("tz")
+
+	loc := time.UTC
+	if event.TZ != "" {
+		loc, err = time.LoadLocation(event.TZ)
+		if err != nil {
+			verr.Add("tz", fmt.Sprintf("unknown time zone %q", event.TZ))
+			loc = time.UTC
+		}
+	}
+
+	// dateField - parses date in time zone of event
+	dateField := func(name, value string) (model.Date, bool) {
+		date, err := model.ParseDate(value, loc)
+		if err != nil {
+			verr.Add(name, err.Error())
+			return model.Date{}, false
+		}
+		return model.Date{Time: date.In(loc)}, true
+	}
+
+	if value := form.Get("date"); value != "" {
+		event.Date, _ = dateField("date", value)
+	}
+
+	if value := form.Get("end"); value != "" {
+		if end, ok := dateField("end", value); ok {
+			event.End = &end
+		}
+	}
+
+	if value := form.Get("rrule"); value != "" {
+		recurrence, err := ical.ParseRRule(value, loc)
+		if err != nil {
+			verr.Add("rrule", err.Error())
+		}
+		event.Recurrence = recurrence
+	}
+
+	for _, values := range form["attendees"] {
+		for _, value := range strings.Split(values, ",") {
+			userID, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				verr.Add("attendees", fmt.Sprintf("%q is not integer", value))
+				continue
+			}
+			event.Attendees = append(event.Attendees, model.Attendee{UserID: userID})
+		}
+	}
+
+	for _, values := range form["reminders"] {
+		for _, value := range strings.Split(values, ",") {
+			minutes, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				verr.Add("reminders", fmt.Sprintf("%q is not integer", value))
+				continue
+			}
+			event.Reminders = append(event.Reminders, model.Reminder{MinutesBefore: minutes})
+		}
+	}
+
+	for _, values := range form["exceptions"] {
+		for _, value := range strings.Split(values, ",") {
+			if exception, ok := dateField("exceptions", strings.TrimSpace(value)); ok {
+				event.Exceptions = append(event.Exceptions, exception)
+			}
+		}
+	}
+
+	if err = verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// decodeErrorResponse - responds to error of decodeEvent
+func (h *Handler) decodeErrorResponse(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnsupportedMediaType) {
+		h.errorResponse(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	h.errorResponse(w, fmt.Errorf("error while decoding input value: %w", err), bodyErrorStatus(err, http.StatusBadRequest))
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"main.go/internal/model"
+	"main.go/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FreeBusy - busy time of one user
+type FreeBusy = service.FreeBusy
+
+// FreeBusyResponse - busy time of users in order of request
+type FreeBusyResponse struct {
+	Result []FreeBusy `json:"result"`
+}
+
+// SlotResponse - suggested time of meeting
+type SlotResponse struct {
+	Result model.Interval `json:"result"`
+}
+
+// GetFreeBusy - returns busy intervals in [from, to) of every user from user_ids, events themselves are not shown.
+// Intervals are given in time zone of from or tz parameter
+func (h *Handler) GetFreeBusy(w http.ResponseWriter, r *http.Request) {
+	userIDs, from, to, err := h.freeBusyQuery(r)
+	if err != nil {
+		h.errorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	busy, err := h.eventService.GetFreeBusy(r.Context(), userIDs, from, to)
+	if err != nil {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	h.jsonResponse(w, &FreeBusyResponse{Result: busy})
+}
+
+// SuggestSlot - returns the earliest slot of duration in [from, to) when all users from user_ids are free
+// and inside their working hours. Duration is like 30m or 1h30m
+func (h *Handler) SuggestSlot(w http.ResponseWriter, r *http.Request) {
+	userIDs, from, to, err := h.freeBusyQuery(r)
+	if err != nil {
+		h.errorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	duration, err := time.ParseDuration(r.URL.Query().Get("duration"))
+	if err != nil || duration <= 0 {
+		h.errorResponse(w, errors.New("duration should be positive, like 30m or 1h30m"), http.StatusBadRequest)
+		return
+	}
+
+	slot, err := h.eventService.SuggestSlot(r.Context(), userIDs, from, to, duration)
+	if err != nil {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	h.jsonResponse(w, &SlotResponse{Result: slot})
+}
+
+// freeBusyQuery - returns users and period of request, user_ids are separated by commas.
+// Number of users and length of period are checked by service
+func (h *Handler) freeBusyQuery(r *http.Request) ([]int, time.Time, time.Time, error) {
+	query := r.URL.Query()
+
+	var userIDs []int
+	for _, value := range query["user_ids"] {
+		for _, id := range strings.Split(value, ",") {
+			uID, err := strconv.Atoi(strings.TrimSpace(id))
+			if err != nil || uID < 1 {
+				return nil, time.Time{}, time.Time{}, errors.New("user_ids should be positive numbers separated by commas")
+			}
+			userIDs = append(userIDs, uID)
+		}
+	}
+
+	from, err := h.ParseDateIn(query.Get("from"), query.Get("tz"))
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+
+	to, err := h.ParseDateIn(query.Get("to"), query.Get("tz"))
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+
+	return userIDs, from, to, nil
+}
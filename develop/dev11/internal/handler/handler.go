@@ -1,128 +1,212 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"main.go/internal/auth"
 	"main.go/internal/model"
+	"main.go/internal/service"
 	"main.go/internal/storage"
+	"main.go/internal/stream"
 	"net/http"
 	"strconv"
 	"time"
 )
 
-// Store - interface for working with data store
-type Store interface {
-	CreateEvent(event *model.Event) error
-	UpdateEvent(userID, eventID int, newEvent *model.Event) error
-	DeleteEvent(userID, eventID int)
-	GetEventsForWeek(date time.Time, userID int) ([]model.Event, error)
-	GetEventsForDay(date time.Time, userID int) ([]model.Event, error)
-	GetEventsForMonth(date time.Time, userID int) ([]model.Event, error)
-}
-
 // ResultResponse - result response struct
 type ResultResponse struct {
 	Result []model.Event `json:"result"`
+	// NextCursor - cursor of the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // ErrorResponse - error response struct
 type ErrorResponse struct {
 	Err string `json:"error"`
+	// Fields - problems of input fields, key is field name
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
 // Handler - http handler struct
 type Handler struct {
-	eventService Store
+	eventService *service.Service
+	idempotency  *idempotencyCache
+	auth         *auth.Authenticator
+	broker       *stream.Broker
+	// maxBodyBytes - limit of request body, bigger bodies get 413
+	maxBodyBytes int64
+	// idempotencyKeys - how many responses of requests with Idempotency-Key are remembered
+	idempotencyKeys int
 }
 
-// NewHandler - creates new handler instance
+// Option - optional behaviour of handler
+type Option func(h *Handler)
+
+// NewHandler - creates new handler instance with in-memory data store and event stream
 func NewHandler() *Handler {
-	return &Handler{
-		eventService: storage.NewEventStorage(),
-	}
+	broker := stream.NewBroker(stream.DefaultHistory)
+	return NewHandlerWithStore(storage.NewEventStorage(storage.WithBroker(broker)), WithStream(broker))
+}
+
+// NewHandlerWithStore - creates new handler instance working with given data store
+func NewHandlerWithStore(store service.Store, opts ...Option) *Handler {
+	return NewHandlerWithService(service.New(store), opts...)
 }
 
-// Register registers all routes to mux
-func (h *Handler) Register(mux *http.ServeMux) {
-	mux.HandleFunc("/create_event", h.CreateEvent)
-	mux.HandleFunc("/update_event", h.UpdateEvent)
-	mux.HandleFunc("/delete_event", h.DeleteEvent)
-	mux.HandleFunc("/events_for_day", h.GetEventsForDay)
-	mux.HandleFunc("/events_for_week", h.GetEventsForWeek)
-	mux.HandleFunc("/events_for_month", h.GetEventsForMonth)
+// NewHandlerWithService - creates new handler instance, HTTP front-end of given service
+func NewHandlerWithService(svc *service.Service, opts ...Option) *Handler {
+	h := &Handler{
+		eventService:    svc,
+		maxBodyBytes:    defaultMaxBodyBytes,
+		idempotencyKeys: defaultIdempotencyKeys,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.idempotency = newIdempotencyCache(h.idempotencyKeys)
+
+	return h
 }
 
-// CreateEvent - gets request data and passes to the service for creating
+// CreateEvent - gets request data and passes to the service for creating.
+// Event without id gets id from the server, request with Idempotency-Key header is executed only once
 func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
-	event, err := h.decodeJSON(r)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.errorResponse(w, fmt.Errorf("error while decoding input value: %v", err), http.StatusBadRequest)
+		h.errorResponse(w, fmt.Errorf("error while reading request: %v", err), bodyErrorStatus(err, http.StatusBadRequest))
 		return
 	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
 
-	err = h.eventService.CreateEvent(event)
+	event, err := h.decodeEvent(r)
 	if err != nil {
-		h.errorResponse(w, fmt.Errorf("error while : %v", err), http.StatusServiceUnavailable)
+		h.decodeErrorResponse(w, err)
 		return
 	}
 
+	key := r.Header.Get(idempotencyHeader)
+	if key == "" {
+		h.createEvent(w, r, event)
+		return
+	}
+
+	// requests are remembered per user, so acting user takes place of omitted one
+	event.UserID, err = service.Acting(r.Context(), event.UserID)
+	if err != nil {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	saved, err := h.idempotency.begin(event.UserID, key, body)
+	switch {
+	case errors.Is(err, errRequestInProgress):
+		h.errorResponse(w, err, http.StatusConflict)
+		return
+	case err != nil:
+		h.errorResponse(w, err, http.StatusUnprocessableEntity)
+		return
+	case saved != nil:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(saved.status)
+		_, _ = w.Write(saved.body)
+		return
+	}
+
+	rec := &responseRecorder{ResponseWriter: w}
+	h.createEvent(rec, r, event)
+	h.idempotency.finish(event.UserID, key, rec.status, rec.body.Bytes())
+}
+
+// createEvent - passes decoded event to the service for creating
+func (h *Handler) createEvent(w http.ResponseWriter, r *http.Request, event *model.Event) {
+	err := h.eventService.CreateEvent(r.Context(), event)
+	if err != nil {
+		h.serviceErrorResponse(w, err, quotaStatus(err, http.StatusServiceUnavailable))
+		return
+	}
+
+	w.Header().Set("ETag", etag(event.Version))
 	h.resultResponse(w, []model.Event{*event})
 }
 
-// DeleteEvent - gets request data and passes to the service for deleting
+// DeleteEvent - gets request data and passes to the service for deleting, deleted event goes to trash
+// and is returned. Version of event is taken from If-Match header or version field
 func (h *Handler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
-	event, err := h.decodeJSON(r)
+	event, err := h.decodeEvent(r)
 	if err != nil {
-		h.errorResponse(w, fmt.Errorf("error while decoding input value: %v", err), http.StatusBadRequest)
+		h.decodeErrorResponse(w, err)
+		return
+	}
+
+	version, fromHeader, ok := h.requestVersion(w, r, event.Version)
+	if !ok {
 		return
 	}
 
-	h.eventService.DeleteEvent(event.UserID, event.EventID)
+	deleted, err := h.eventService.DeleteEvent(r.Context(), event.UserID, event.EventID, version)
+	if err != nil {
+		if !h.versionErrorResponse(w, err, fromHeader) {
+			h.storeErrorResponse(w, err)
+		}
+		return
+	}
 
-	h.resultResponse(w, []model.Event{*event})
+	h.resultResponse(w, []model.Event{deleted})
 }
 
-// UpdateEvent - gets request data and passes to the service for updating
+// UpdateEvent - gets request data and passes to the service for updating.
+// Version of event is taken from If-Match header or version field, response has the new one
 func (h *Handler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
-	event, err := h.decodeJSON(r)
+	event, err := h.decodeEvent(r)
 	if err != nil {
-		h.errorResponse(w, fmt.Errorf("error while decoding input value: %v", err), http.StatusBadRequest)
+		h.decodeErrorResponse(w, err)
 		return
 	}
 
-	err = h.eventService.UpdateEvent(event.UserID, event.EventID, event)
+	version, fromHeader, ok := h.requestVersion(w, r, event.Version)
+	if !ok {
+		return
+	}
+	event.Version = version
+
+	err = h.eventService.UpdateEvent(r.Context(), event)
 	if err != nil {
-		h.errorResponse(w, err, http.StatusServiceUnavailable)
+		if !h.versionErrorResponse(w, err, fromHeader) {
+			h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		}
 		return
 	}
 
+	w.Header().Set("ETag", etag(event.Version))
 	h.resultResponse(w, []model.Event{*event})
 }
 
 // GetEventsForDay - gets request for event for day and  returns slice of events
+// Window of day is taken in time zone from tz parameter
 func (h *Handler) GetEventsForDay(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
 	date := r.URL.Query().Get("date")
 
-	uID, err := strconv.Atoi(userID)
-	if err != nil || uID < 1 {
-		if uID < 1 {
-			err = errors.New("userID should be positive")
-		}
-		h.errorResponse(w, err, http.StatusBadRequest)
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
 		return
 	}
 
-	eventDate, err := h.ParseDate(date)
+	eventDate, err := h.ParseDateIn(date, r.URL.Query().Get("tz"))
 	if err != nil {
 		h.errorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
-	events, err := h.eventService.GetEventsForDay(eventDate, uID)
+	events, err := h.eventService.GetEventsForDay(r.Context(), uID, eventDate)
 	if err != nil {
-		h.errorResponse(w, err, http.StatusServiceUnavailable)
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
 		return
 	}
 
@@ -130,28 +214,25 @@ func (h *Handler) GetEventsForDay(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetEventsForWeek - gets request for event for week and  returns slice of events
+// Window of week is taken in time zone from tz parameter
 func (h *Handler) GetEventsForWeek(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
 	date := r.URL.Query().Get("date")
 
-	uID, err := strconv.Atoi(userID)
-	if err != nil || uID < 1 {
-		if uID < 1 {
-			err = errors.New("userID should be positive")
-		}
-		h.errorResponse(w, err, http.StatusBadRequest)
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
 		return
 	}
 
-	eventDate, err := h.ParseDate(date)
+	eventDate, err := h.ParseDateIn(date, r.URL.Query().Get("tz"))
 	if err != nil {
 		h.errorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
-	events, err := h.eventService.GetEventsForWeek(eventDate, uID)
+	events, err := h.eventService.GetEventsForWeek(r.Context(), uID, eventDate)
 	if err != nil {
-		h.errorResponse(w, err, http.StatusServiceUnavailable)
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
 		return
 	}
 
@@ -159,55 +240,82 @@ func (h *Handler) GetEventsForWeek(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetEventsForMonth - gets request for event for month and  returns slice of events
+// Window of month is taken in time zone from tz parameter
 func (h *Handler) GetEventsForMonth(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
 	date := r.URL.Query().Get("date")
 
-	uID, err := strconv.Atoi(userID)
-	if err != nil || uID < 1 {
-		if uID < 1 {
-			err = errors.New("userID should be positive")
-		}
-		h.errorResponse(w, err, http.StatusBadRequest)
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
 		return
 	}
 
-	eventDate, err := h.ParseDate(date)
+	eventDate, err := h.ParseDateIn(date, r.URL.Query().Get("tz"))
 	if err != nil {
 		h.errorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
-	events, err := h.eventService.GetEventsForMonth(eventDate, uID)
+	events, err := h.eventService.GetEventsForMonth(r.Context(), uID, eventDate)
 	if err != nil {
-		h.errorResponse(w, err, http.StatusServiceUnavailable)
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
 		return
 	}
 
 	h.resultResponse(w, events)
 }
 
-// decodeJSON - decode json format from request and returns event
-func (h *Handler) decodeJSON(r *http.Request) (*model.Event, error) {
-	var event model.Event
+// GetConflicts - gets time interval and returns events of user which overlap it.
+// Event with event_id is skipped, so conflicts of existing event can be checked
+func (h *Handler) GetConflicts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
 
-	err := json.NewDecoder(r.Body).Decode(&event)
+	uID, err := h.userID(r)
 	if err != nil {
-		return nil, err
+		h.userErrorResponse(w, err)
+		return
 	}
 
-	if event.UserID < 1 || event.EventID < 1 {
-		return nil, errors.New("eventID or userID should pe positive")
+	var eventID int
+	if query.Has("event_id") {
+		eventID, err = strconv.Atoi(query.Get("event_id"))
+		if err != nil {
+			h.errorResponse(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	start, err := h.ParseDateIn(query.Get("start"), query.Get("tz"))
+	if err != nil {
+		h.errorResponse(w, err, http.StatusBadRequest)
+		return
 	}
 
-	return &event, nil
+	end, err := h.ParseDateIn(query.Get("end"), query.Get("tz"))
+	if err != nil {
+		h.errorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.eventService.GetConflicts(r.Context(), uID, start, end, eventID)
+	if err != nil {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	h.resultResponse(w, events)
 }
 
 // ResultResponse - positive response
 func (h *Handler) resultResponse(w http.ResponseWriter, events []model.Event) {
+	h.jsonResponse(w, &ResultResponse{Result: events})
+}
+
+// jsonResponse - positive response with any document
+func (h *Handler) jsonResponse(w http.ResponseWriter, response interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 
-	result, _ := json.MarshalIndent(&ResultResponse{Result: events}, " ", "")
+	result, _ := json.MarshalIndent(response, " ", "")
 	_, err := w.Write(result)
 	if err != nil {
 		h.errorResponse(w, err, http.StatusInternalServerError)
@@ -217,29 +325,36 @@ func (h *Handler) resultResponse(w http.ResponseWriter, events []model.Event) {
 
 // ErrorResponse - response with error status
 func (h *Handler) errorResponse(w http.ResponseWriter, err error, status int) {
+	writeError(w, err, status)
+}
+
+// writeError - writes ErrorResponse with status, problems of fields are taken from *model.ValidationError
+func writeError(w http.ResponseWriter, err error, status int) {
 	w.Header().Set("Content-Type", "application/json")
 
-	jsonErr, _ := json.MarshalIndent(&ErrorResponse{Err: err.Error()}, " ", " ")
-	http.Error(w, string(jsonErr), status)
+	response := ErrorResponse{Err: err.Error()}
+
+	var verr *model.ValidationError
+	if errors.As(err, &verr) {
+		response.Fields = verr.Fields
+	}
+
+	jsonErr, _ := json.MarshalIndent(&response, " ", " ")
+
+	// http.Error would send json as text/plain
+	w.Header().Del("Content-Length")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintln(w, string(jsonErr))
 }
 
-// ParseDate - parsing date from string
+// ParseDate - parsing date from string, date without offset is read in UTC
 func (h *Handler) ParseDate(date string) (time.Time, error) {
-	var (
-		eventDate time.Time
-		err       error
-	)
-
-	eventDate, err = time.Parse("2006-01-02T15:04", date)
-	if err != nil {
-		eventDate, err = time.Parse("2006-01-02", date)
-		if err != nil {
-			eventDate, err = time.Parse("2006-01-02T15:04:00Z", date)
-			if err != nil {
-				return time.Time{}, fmt.Errorf("date format: e.g. 2022-05-10T14:10 error: %v", err)
-			}
-		}
-	}
+	return model.ParseDate(date, time.UTC)
+}
 
-	return eventDate, nil
+// ParseDateIn - parsing date from string and moving it to time zone tz.
+// Date without offset is read in tz, empty tz keeps offset of date or UTC
+func (h *Handler) ParseDateIn(date, tz string) (time.Time, error) {
+	return service.ParseDate(date, tz)
 }
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"main.go/internal/ical"
+	"main.go/internal/model"
+	"mime"
+	"net/http"
+)
+
+// maxCalendarSize - limit of uploaded calendar file
+const maxCalendarSize = 10 << 20
+
+// ExportICS - returns all events of user as iCalendar file
+func (h *Handler) ExportICS(w http.ResponseWriter, r *http.Request) {
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	events, err := h.eventService.GetEvents(r.Context(), uID)
+	if err != nil {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+
+	err = ical.Encode(w, events)
+	if err != nil {
+		h.errorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// ImportResponse - imported events and events of calendar which are not imported
+type ImportResponse struct {
+	Result  []model.Event  `json:"result"`
+	Skipped []ical.Skipped `json:"skipped,omitempty"`
+}
+
+// ImportICS - gets iCalendar file in request body or in "file" field of multipart form
+// and creates its events for user, events get new ids. Nothing is created if any event is wrong.
+// Events with rules server can not repeat are skipped and listed in response
+func (h *Handler) ImportICS(w http.ResponseWriter, r *http.Request) {
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	file, err := h.calendarFile(r)
+	if err != nil {
+		h.errorResponse(w, fmt.Errorf("error while reading calendar: %v", err), bodyErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+	defer file.Close()
+
+	events, skipped, err := ical.Decode(file)
+	if err != nil {
+		h.errorResponse(w, fmt.Errorf("error while decoding calendar: %v", err), bodyErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	err = h.eventService.ImportEvents(r.Context(), uID, events)
+	if err != nil {
+		h.serviceErrorResponse(w, err, quotaStatus(err, http.StatusServiceUnavailable))
+		return
+	}
+
+	h.jsonResponse(w, &ImportResponse{Result: events, Skipped: skipped})
+}
+
+// calendarFile - returns uploaded calendar, its size is limited by bodyLimits
+func (h *Handler) calendarFile(r *http.Request) (io.ReadCloser, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// idempotencyHeader - header with client generated key of retried request
+	idempotencyHeader = "Idempotency-Key"
+	// idempotencyTTL - how long response for key is remembered
+	idempotencyTTL = 24 * time.Hour
+	// defaultIdempotencyKeys - how many responses are remembered at once, the oldest ones are evicted
+	defaultIdempotencyKeys = 10000
+	// idempotencySweepInterval - how often expired responses are removed while cache is not empty
+	idempotencySweepInterval = time.Minute
+)
+
+var (
+	errRequestInProgress = errors.New("request with such idempotency key is in progress")
+	errKeyReused         = errors.New("idempotency key is already used for another request")
+)
+
+// WithIdempotencyKeys - sets how many responses of requests with Idempotency-Key are remembered,
+// the oldest ones are evicted first
+func WithIdempotencyKeys(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.idempotencyKeys = n
+		}
+	}
+}
+
+// savedResponse - response sent for request with idempotency key
+type savedResponse struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	body        []byte
+	expires     time.Time
+	// elem - place of response in order of expiry
+	elem *list.Element
+}
+
+// idempotencyCache - remembers responses of requests with idempotency key, so retries are not executed twice.
+// Cache is kept in memory only, so keys are forgotten on restart and retry after it is executed again
+type idempotencyCache struct {
+	sync.Mutex
+	responses map[string]*savedResponse
+	// order - keys from the earliest expiring one, all of them live for idempotencyTTL
+	order   *list.List
+	maxKeys int
+	// sweeper - removes expired responses while cache is not empty
+	sweeper *time.Timer
+}
+
+// newIdempotencyCache - creates empty cache remembering up to maxKeys responses
+func newIdempotencyCache(maxKeys int) *idempotencyCache {
+	return &idempotencyCache{
+		responses: make(map[string]*savedResponse),
+		order:     list.New(),
+		maxKeys:   maxKeys,
+	}
+}
+
+// begin - reserves key for request with given body.
+// Returns saved response if request with the same key and body is already done
+func (c *idempotencyCache) begin(userID int, key string, body []byte) (*savedResponse, error) {
+	cacheKey := strconv.Itoa(userID) + ":" + key
+	fingerprint := sha256.Sum256(body)
+	now := time.Now()
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.purge(now)
+
+	saved, ok := c.responses[cacheKey]
+	if !ok {
+		c.add(cacheKey, &savedResponse{fingerprint: fingerprint, expires: now.Add(idempotencyTTL)})
+		return nil, nil
+	}
+
+	if saved.fingerprint != fingerprint {
+		return nil, errKeyReused
+	}
+	if !saved.done {
+		return nil, errRequestInProgress
+	}
+
+	return saved, nil
+}
+
+// finish - saves response for reserved key. Failed requests release key, so they can be retried
+func (c *idempotencyCache) finish(userID int, key string, status int, body []byte) {
+	cacheKey := strconv.Itoa(userID) + ":" + key
+
+	c.Lock()
+	defer c.Unlock()
+
+	saved, ok := c.responses[cacheKey]
+	if !ok {
+		return
+	}
+
+	if status >= http.StatusBadRequest {
+		c.remove(cacheKey)
+		return
+	}
+
+	saved.done = true
+	saved.status = status
+	saved.body = body
+}
+
+// add - remembers response for key, the oldest one is evicted when cache is full. Must be called under lock
+func (c *idempotencyCache) add(key string, saved *savedResponse) {
+	for len(c.responses) >= c.maxKeys {
+		c.remove(c.order.Front().Value.(string))
+	}
+
+	saved.elem = c.order.PushBack(key)
+	c.responses[key] = saved
+
+	if c.sweeper == nil {
+		c.sweeper = time.AfterFunc(idempotencySweepInterval, c.sweep)
+	}
+}
+
+// remove - forgets response for key, must be called under lock
+func (c *idempotencyCache) remove(key string) {
+	saved, ok := c.responses[key]
+	if !ok {
+		return
+	}
+
+	c.order.Remove(saved.elem)
+	delete(c.responses, key)
+}
+
+// sweep - removes expired responses and runs again while cache is not empty,
+// so they do not wait for the next request
+func (c *idempotencyCache) sweep() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.purge(time.Now())
+
+	if len(c.responses) == 0 {
+		c.sweeper = nil
+		return
+	}
+	c.sweeper.Reset(idempotencySweepInterval)
+}
+
+// purge - removes expired responses, must be called under lock
+func (c *idempotencyCache) purge(now time.Time) {
+	for elem := c.order.Front(); elem != nil; elem = c.order.Front() {
+		key := elem.Value.(string)
+		if !now.After(c.responses[key].expires) {
+			return
+		}
+		c.remove(key)
+	}
+}
+
+// responseRecorder - passes response to client and keeps its copy
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader - remembers status code
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write - remembers body
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
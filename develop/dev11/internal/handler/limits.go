@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"main.go/internal/service"
+	"net/http"
+)
+
+// defaultMaxBodyBytes - limit of request body when handler is created without WithMaxBodyBytes
+const defaultMaxBodyBytes = 1 << 20
+
+// bodyLimits - routes which accept bodies bigger than limit of handler
+var bodyLimits = map[string]int64{
+	"/import_ics": maxCalendarSize,
+}
+
+// WithMaxBodyBytes - sets limit of request body, reading of bigger body fails and request gets 413
+func WithMaxBodyBytes(n int64) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxBodyBytes = n
+		}
+	}
+}
+
+// limitBody - limits request body of route
+func (h *Handler) limitBody(path string, next http.HandlerFunc) http.HandlerFunc {
+	limit := h.maxBodyBytes
+	if routeLimit, ok := bodyLimits[path]; ok && routeLimit > limit {
+		limit = routeLimit
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(w, r)
+	}
+}
+
+// bodyErrorStatus - returns 413 if error is caused by body bigger than limit, otherwise status
+func bodyErrorStatus(err error, status int) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return status
+}
+
+// quotaStatus - returns 507 if user has no quota for one more event, otherwise status.
+// It is not 429, waiting does not free quota, only deleting events does
+func quotaStatus(err error, status int) int {
+	if service.KindOf(err) == service.KindQuota {
+		return http.StatusInsufficientStorage
+	}
+	return status
+}
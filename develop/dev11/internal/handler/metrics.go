@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"main.go/internal/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// knownMethods - methods which get own label, the rest are counted together
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// Metrics - counts requests and measures their latency by route of mux, method and status.
+// next should be the mux from Register, so the route is known after request is served
+func Metrics(reg *metrics.Registry, next http.Handler) http.Handler {
+	requests := reg.NewCounterVec("http_requests_total",
+		"Number of served requests by route, method and status code.", "route", "method", "status")
+	latency := reg.NewHistogramVec("http_request_duration_seconds",
+		"Time of serving requests by route and method.", metrics.DefaultBuckets, "route", "method")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, req)
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		// mux sets pattern of matched route, unknown paths share one label
+		route := req.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+
+		method := req.Method
+		if !knownMethods[method] {
+			method = "OTHER"
+		}
+
+		requests.Inc(route, method, strconv.Itoa(sw.status))
+		latency.Observe(time.Since(start).Seconds(), route, method)
+	})
+}
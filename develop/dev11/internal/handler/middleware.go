@@ -1,15 +1,135 @@
 package handler
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
-func Logging(next http.Handler) http.Handler {
+// requestIDHeader - header with id of request, it is taken from client or generated
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength - longer ids from client are replaced with generated ones
+const maxRequestIDLength = 128
+
+// contextKey - key of values put into request context
+type contextKey int
+
+// requestInfoKey - key of requestInfo
+const requestInfoKey contextKey = 0
+
+// requestInfo - data of request found out by handlers, written to access log
+type requestInfo struct {
+	requestID string
+	userID    int
+}
+
+// Logging - writes access log record for every request and sets X-Request-ID header of response
+func Logging(logger *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, req)
-		log.Printf("method: %s  URI: %s  time: %s", req.Method, req.RequestURI, time.Since(start))
+
+		info := &requestInfo{requestID: requestID(req)}
+		w.Header().Set(requestIDHeader, info.requestID)
+
+		sw := &statusWriter{ResponseWriter: w}
+		req = req.WithContext(context.WithValue(req.Context(), requestInfoKey, info))
+
+		next.ServeHTTP(sw, req)
+
+		// handler which has written nothing responds 200
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", info.requestID),
+			slog.String("method", req.Method),
+			slog.String("uri", req.RequestURI),
+			slog.Int("status", sw.status),
+			slog.Int("bytes", sw.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote_addr", req.RemoteAddr),
+		}
+		if info.userID != 0 {
+			attrs = append(attrs, slog.Int("user_id", info.userID))
+		}
+
+		level := slog.LevelInfo
+		if sw.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		logger.LogAttrs(req.Context(), level, "request", attrs...)
 	})
 }
+
+// RequestID - returns id of request given by Logging, empty outside of it
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey).(*requestInfo); ok {
+		return info.requestID
+	}
+	return ""
+}
+
+// logUser - remembers user of request for access log
+func logUser(r *http.Request, userID int) {
+	if info, ok := r.Context().Value(requestInfoKey).(*requestInfo); ok {
+		info.userID = userID
+	}
+}
+
+// requestID - returns id sent by client or new random id
+func requestID(r *http.Request) string {
+	id := r.Header.Get(requestIDHeader)
+	if id != "" && len(id) <= maxRequestIDLength && printable(id) {
+		return id
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// printable - checks that string has only visible ASCII characters, so it can not break log
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] <= ' ' || s[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// statusWriter - passes response to client counting status and size
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// WriteHeader - remembers status code
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write - counts written bytes
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap - returns original writer for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
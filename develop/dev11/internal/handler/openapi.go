@@ -0,0 +1,20 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPIPath - path of OpenAPI document, it is served without authentication
+const openAPIPath = "/openapi.json"
+
+// openAPISpec - OpenAPI 3 document describing all routes of Register
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI - returns OpenAPI document of API
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPISpec)
+}
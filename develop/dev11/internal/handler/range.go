@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"main.go/internal/service"
+	"net/http"
+	"strconv"
+)
+
+// GetEventsInRange - returns page of user events which overlap [from, to) in chronological order.
+// Next page is requested with cursor from previous response. User is taken from path or user_id parameter
+func (h *Handler) GetEventsInRange(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	from, err := h.ParseDateIn(query.Get("from"), query.Get("tz"))
+	if err != nil {
+		h.errorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	to, err := h.ParseDateIn(query.Get("to"), query.Get("tz"))
+	if err != nil {
+		h.errorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var limit int
+	if query.Has("limit") {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 {
+			h.errorResponse(w, service.ErrPageLimit, http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := h.eventService.GetEventsInRange(r.Context(), uID, from, to, query.Get("cursor"), limit)
+	if err != nil {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	h.jsonResponse(w, &ResultResponse{Result: page.Events, NextCursor: page.NextCursor})
+}
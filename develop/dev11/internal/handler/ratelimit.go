@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitPurgeInterval - how often buckets of clients which stopped sending requests are removed
+const rateLimitPurgeInterval = time.Minute
+
+// errRateLimited - client has sent more requests than its rate allows
+var errRateLimited = errors.New("too many requests, retry later")
+
+// RateLimiter - token bucket of every client: bucket holds up to burst tokens, it is refilled
+// with rate tokens per second and every request takes one token
+type RateLimiter struct {
+	sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	// lastPurge - time when full buckets were removed last time
+	lastPurge time.Time
+}
+
+// bucket - tokens of one client at the moment of its last request
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter - creates limiter allowing rate requests per second and burst requests at once to every client
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastPurge: time.Now(),
+	}
+}
+
+// Allow - takes token of client, when there is none returns time until the next one
+func (l *RateLimiter) Allow(client string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.purge(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+
+	return true, 0
+}
+
+// purge - removes buckets which are full again, they do not differ from new ones. Must be called under lock
+func (l *RateLimiter) purge(now time.Time) {
+	if now.Sub(l.lastPurge) < rateLimitPurgeInterval {
+		return
+	}
+	l.lastPurge = now
+
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// RateLimit - responds 429 with Retry-After header to client which exceeded its rate, client is told by IP address.
+// Nil limiter passes all requests
+func RateLimit(l *RateLimiter, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.Allow(clientIP(r))
+		if !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeError(w, errRateLimited, http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP - returns address of client without port, forwarding headers are not trusted
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"main.go/internal/model"
+	"main.go/internal/service"
+	"net/http"
+	"strconv"
+)
+
+// GetEvent - returns event from path /users/{id}/events/{eventID}, recurring event is not expanded
+func (h *Handler) GetEvent(w http.ResponseWriter, r *http.Request) {
+	uID, eventID, err := h.eventPath(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	event, err := h.eventService.GetEvent(r.Context(), uID, eventID)
+	if err != nil {
+		h.storeErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(event.Version))
+	h.resultResponse(w, []model.Event{event})
+}
+
+// PutEvent - replaces event from path /users/{id}/events/{eventID} with request body.
+// Version of event is taken from If-Match header or version field, response has the new one
+func (h *Handler) PutEvent(w http.ResponseWriter, r *http.Request) {
+	event, err := h.decodeEvent(r)
+	if err != nil {
+		h.decodeErrorResponse(w, err)
+		return
+	}
+
+	version, fromHeader, ok := h.requestVersion(w, r, event.Version)
+	if !ok {
+		return
+	}
+	event.Version = version
+
+	err = h.eventService.UpdateEvent(r.Context(), event)
+	if err != nil {
+		if !h.versionErrorResponse(w, err, fromHeader) {
+			h.storeErrorResponse(w, err)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", etag(event.Version))
+	h.resultResponse(w, []model.Event{*event})
+}
+
+// RemoveEvent - moves event from path /users/{id}/events/{eventID} to trash and returns it.
+// Version of event is taken from If-Match header
+func (h *Handler) RemoveEvent(w http.ResponseWriter, r *http.Request) {
+	uID, eventID, err := h.eventPath(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	version, fromHeader, ok := h.requestVersion(w, r, 0)
+	if !ok {
+		return
+	}
+
+	event, err := h.eventService.DeleteEvent(r.Context(), uID, eventID, version)
+	if err != nil {
+		if !h.versionErrorResponse(w, err, fromHeader) {
+			h.storeErrorResponse(w, err)
+		}
+		return
+	}
+
+	h.resultResponse(w, []model.Event{event})
+}
+
+// userID - returns user from path /users/{id} or from user_id query parameter.
+// Authenticated user may omit it, but can not ask for another user
+func (h *Handler) userID(r *http.Request) (int, error) {
+	value := r.PathValue("id")
+	if value == "" {
+		value = r.URL.Query().Get("user_id")
+	}
+
+	// missing user is zero, service takes acting user in its place
+	uID, err := strconv.Atoi(value)
+	if err != nil && value != "" {
+		return 0, errors.New("userID should be positive")
+	}
+
+	uID, err = h.eventService.User(r.Context(), uID)
+	if err != nil {
+		return 0, err
+	}
+
+	logUser(r, uID)
+
+	return uID, nil
+}
+
+// eventPath - returns user and event from path /users/{id}/events/{eventID}
+func (h *Handler) eventPath(r *http.Request) (int, int, error) {
+	uID, err := h.userID(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	eventID, err := strconv.Atoi(r.PathValue("eventID"))
+	if err != nil || eventID < 1 {
+		return 0, 0, errors.New("eventID should be positive")
+	}
+
+	return uID, eventID, nil
+}
+
+// applyPath - takes ids of event from request path, ids in body may be omitted but should match path
+func (h *Handler) applyPath(r *http.Request, event *model.Event) error {
+	var verr model.ValidationError
+
+	// apply - copies id from path segment to event field
+	apply := func(segment, field string, id *int) {
+		value := r.PathValue(segment)
+		if value == "" {
+			return
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			verr.Add(field, fmt.Sprintf("%q in path is not integer", value))
+			return
+		}
+		if *id != 0 && *id != n {
+			verr.Add(field, fmt.Sprintf("%d does not match %d in path", *id, n))
+			return
+		}
+		*id = n
+	}
+
+	apply("id", "user_id", &event.UserID)
+	apply("eventID", "event_id", &event.EventID)
+
+	return verr.Err()
+}
+
+// userErrorResponse - responds to error of userID, wrong user is 400 and another user is 403
+func (h *Handler) userErrorResponse(w http.ResponseWriter, err error) {
+	h.serviceErrorResponse(w, err, http.StatusBadRequest)
+}
+
+// serviceErrorResponse - responds to error of service, wrong input is 400 and another user is 403,
+// other errors get status
+func (h *Handler) serviceErrorResponse(w http.ResponseWriter, err error, status int) {
+	switch service.KindOf(err) {
+	case service.KindInvalid:
+		h.errorResponse(w, err, http.StatusBadRequest)
+	case service.KindForbidden:
+		h.errorResponse(w, err, http.StatusForbidden)
+	default:
+		h.errorResponse(w, err, status)
+	}
+}
+
+// storeErrorResponse - responds to error of service about stored event, missing event is 404,
+// exceeded quota is 507
+func (h *Handler) storeErrorResponse(w http.ResponseWriter, err error) {
+	if service.KindOf(err) == service.KindNotFound {
+		h.errorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	h.serviceErrorResponse(w, err, quotaStatus(err, http.StatusServiceUnavailable))
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// route - endpoint of API
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+}
+
+// routes - returns all endpoints of API, legacy ones and REST resources
+func (h *Handler) routes() []route {
+	return []route{
+		{http.MethodPost, "/create_event", h.CreateEvent},
+		{http.MethodPost, "/update_event", h.UpdateEvent},
+		{http.MethodPost, "/delete_event", h.DeleteEvent},
+		{http.MethodGet, "/events_for_day", h.GetEventsForDay},
+		{http.MethodGet, "/events_for_week", h.GetEventsForWeek},
+		{http.MethodGet, "/events_for_month", h.GetEventsForMonth},
+		{http.MethodGet, "/conflicts", h.GetConflicts},
+		{http.MethodGet, "/calendar.ics", h.ExportICS},
+		{http.MethodPost, "/import_ics", h.ImportICS},
+		{http.MethodGet, "/events", h.GetEventsInRange},
+		{http.MethodGet, "/events/stream", h.StreamEvents},
+		{http.MethodPost, "/respond_event", h.RespondEvent},
+		{http.MethodGet, "/invitations", h.GetInvitations},
+		{http.MethodGet, "/search", h.SearchEvents},
+		{http.MethodPost, "/batch", h.Batch},
+		{http.MethodGet, "/events/{eventID}/history", h.GetHistory},
+		{http.MethodPost, "/events/{eventID}/restore", h.RestoreEvent},
+		{http.MethodGet, "/trash", h.GetTrash},
+		{http.MethodGet, "/freebusy", h.GetFreeBusy},
+		{http.MethodGet, "/suggest_slot", h.SuggestSlot},
+
+		{http.MethodGet, "/users/{id}/events", h.GetEventsInRange},
+		{http.MethodPost, "/users/{id}/events", h.CreateEvent},
+		{http.MethodGet, "/users/{id}/events/{eventID}", h.GetEvent},
+		{http.MethodPut, "/users/{id}/events/{eventID}", h.PutEvent},
+		{http.MethodDelete, "/users/{id}/events/{eventID}", h.RemoveEvent},
+		{http.MethodGet, "/users/{id}/events/{eventID}/history", h.GetHistory},
+		{http.MethodPost, "/users/{id}/events/{eventID}/restore", h.RestoreEvent},
+		{http.MethodGet, "/users/{id}/trash", h.GetTrash},
+		{http.MethodGet, "/users/{id}/invitations", h.GetInvitations},
+		{http.MethodGet, "/users/{id}/search", h.SearchEvents},
+		{http.MethodPut, "/users/{id}/invitations/{ownerID}/{eventID}", h.RespondEvent},
+	}
+}
+
+// Register registers all routes to mux.
+// Request with method which path does not support gets 405 with Allow header
+func (h *Handler) Register(mux *http.ServeMux) {
+	var paths []string
+	allowed := make(map[string][]string)
+
+	for _, rt := range h.routes() {
+		mux.Handle(rt.method+" "+rt.path, h.Authenticate(h.limitBody(rt.path, rt.handler)))
+
+		if _, ok := allowed[rt.path]; !ok {
+			paths = append(paths, rt.path)
+		}
+		allowed[rt.path] = append(allowed[rt.path], rt.method)
+		// GET pattern serves HEAD too
+		if rt.method == http.MethodGet {
+			allowed[rt.path] = append(allowed[rt.path], http.MethodHead)
+		}
+	}
+
+	// contract of API is public, clients read it before they have credentials
+	mux.HandleFunc(http.MethodGet+" "+openAPIPath, h.OpenAPI)
+	paths = append(paths, openAPIPath)
+	allowed[openAPIPath] = []string{http.MethodGet, http.MethodHead}
+
+	// pattern without method is less specific, it gets only methods not registered above
+	for _, path := range paths {
+		mux.HandleFunc(path, h.methodNotAllowed(allowed[path]))
+	}
+}
+
+// Routes - returns patterns of all endpoints like "GET /events", OpenAPI document describes every one of them
+func (h *Handler) Routes() []string {
+	patterns := make([]string, 0, len(h.routes())+1)
+	for _, rt := range h.routes() {
+		patterns = append(patterns, rt.method+" "+rt.path)
+	}
+	return append(patterns, http.MethodGet+" "+openAPIPath)
+}
+
+// methodNotAllowed - responds 405 listing allowed methods
+func (h *Handler) methodNotAllowed(methods []string) http.HandlerFunc {
+	allow := strings.Join(methods, ", ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		h.errorResponse(w, fmt.Errorf("method %s is not allowed, use %s", r.Method, allow), http.StatusMethodNotAllowed)
+	}
+}
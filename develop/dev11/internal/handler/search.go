@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+)
+
+// SearchEvents - returns events of user with words starting with every word of q.
+// With from and to only occurrences overlapping [from, to) are returned. User is taken from path or user_id parameter
+func (h *Handler) SearchEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	var from, to time.Time
+	if query.Has("from") || query.Has("to") {
+		from, err = h.ParseDateIn(query.Get("from"), query.Get("tz"))
+		if err != nil {
+			h.errorResponse(w, err, http.StatusBadRequest)
+			return
+		}
+
+		to, err = h.ParseDateIn(query.Get("to"), query.Get("tz"))
+		if err != nil {
+			h.errorResponse(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := h.eventService.SearchEvents(r.Context(), uID, query.Get("q"), from, to)
+	if err != nil {
+		h.serviceErrorResponse(w, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	h.resultResponse(w, events)
+}
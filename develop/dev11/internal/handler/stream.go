@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"main.go/internal/stream"
+	"net/http"
+	"time"
+)
+
+// heartbeatInterval - comment is sent so often, proxies do not close idle stream
+const heartbeatInterval = 15 * time.Second
+
+// WithStream - enables /events/stream fed by broker which receives changes of data store
+func WithStream(b *stream.Broker) Option {
+	return func(h *Handler) {
+		h.broker = b
+	}
+}
+
+// StreamEvents - sends changes of user events and events user is invited to as Server-Sent Events.
+// Client reconnecting with Last-Event-ID gets missed changes, if history has no them it gets reset event
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	uID, err := h.userID(r)
+	if err != nil {
+		h.userErrorResponse(w, err)
+		return
+	}
+
+	if h.broker == nil {
+		h.errorResponse(w, errors.New("event stream is not enabled"), http.StatusServiceUnavailable)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	// without Last-Event-ID client gets changes kept in history
+	epoch, lastID := h.broker.Epoch(), uint64(0)
+	if lastEventID != "" {
+		epoch, lastID, err = stream.ParseStreamID(lastEventID)
+		if err != nil {
+			h.errorResponse(w, fmt.Errorf("wrong Last-Event-ID: %q", lastEventID), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	// stream lives longer than write timeout of server
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	replay, complete, changes, cancel := h.broker.Subscribe(uID, epoch, lastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if lastEventID != "" && !complete {
+		// client has to reload events, changes it missed are lost
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+
+	for _, change := range replay {
+		writeChange(w, change)
+	}
+
+	if rc.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case change, ok := <-changes:
+			// closed channel means shutdown or slow client, client reconnects with Last-Event-ID
+			if !ok {
+				return
+			}
+			writeChange(w, change)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+// writeChange - writes change as SSE message with id and type
+func writeChange(w http.ResponseWriter, change stream.Change) {
+	data, _ := json.Marshal(change)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", change.StreamID(), change.Type, data)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"main.go/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errVersionRequired - change of event does not name version it is based on
+var errVersionRequired = fmt.Errorf("%w in If-Match header or in version field", service.ErrVersionRequired)
+
+// expectedVersion - returns version which request changes, it is taken from If-Match header or from body.
+// fromHeader tells where it is from, so stale version is answered with 412 or 409.
+// If-Match: * changes any version and gives service.AnyVersion, missing version is zero and service refuses it
+func expectedVersion(r *http.Request, bodyVersion int) (version int, fromHeader bool, err error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		if bodyVersion < 1 {
+			return 0, false, nil
+		}
+		return bodyVersion, false, nil
+	}
+
+	if header == "*" {
+		return service.AnyVersion, true, nil
+	}
+
+	version, err = parseETag(header)
+	if err != nil {
+		return 0, true, err
+	}
+
+	if bodyVersion != 0 && bodyVersion != version {
+		return 0, true, fmt.Errorf("version %d in body does not match If-Match %s", bodyVersion, header)
+	}
+
+	return version, true, nil
+}
+
+// requestVersion - returns version request changes, responds with error if it is wrong
+func (h *Handler) requestVersion(w http.ResponseWriter, r *http.Request, bodyVersion int) (int, bool, bool) {
+	version, fromHeader, err := expectedVersion(r, bodyVersion)
+	if err != nil {
+		if !h.versionErrorResponse(w, err, fromHeader) {
+			h.errorResponse(w, err, http.StatusBadRequest)
+		}
+		return 0, false, false
+	}
+
+	return version, fromHeader, true
+}
+
+// etag - returns entity tag of event version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseETag - returns version from strong entity tag
+func parseETag(tag string) (int, error) {
+	version, err := strconv.Atoi(strings.Trim(tag, `"`))
+	if err != nil || version < 1 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, fmt.Errorf("wrong If-Match: %s", tag)
+	}
+	return version, nil
+}
+
+// versionErrorResponse - responds to missing or stale version, returns false for other errors
+func (h *Handler) versionErrorResponse(w http.ResponseWriter, err error, fromHeader bool) bool {
+	if errors.Is(err, service.ErrVersionRequired) {
+		h.errorResponse(w, errVersionRequired, http.StatusPreconditionRequired)
+		return true
+	}
+
+	current, ok := service.CurrentVersion(err)
+	if !ok {
+		return false
+	}
+
+	w.Header().Set("ETag", etag(current))
+	if fromHeader {
+		h.errorResponse(w, err, http.StatusPreconditionFailed)
+	} else {
+		h.errorResponse(w, err, http.StatusConflict)
+	}
+	return true
+}
@@ -0,0 +1,436 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"main.go/internal/model"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	utcLayout      = "20060102T150405Z"
+	localLayout    = "20060102T150405"
+	dateOnlyLayout = "20060102"
+
+	// maxLineLength - RFC 5545 limit of line length in octets, longer lines are folded
+	maxLineLength = 75
+
+	prodID = "-//dev11//calendar server//EN"
+)
+
+// Encode - writes events as RFC 5545 calendar.
+// Events with time zone are written with TZID parameter defined by VTIMEZONE component, the rest in UTC
+func Encode(w io.Writer, events []model.Event) error {
+	zones, err := usedZones(events)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(utcLayout)
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:"+prodID)
+	writeLine(bw, "CALSCALE:GREGORIAN")
+
+	for _, zone := range zones {
+		writeTimezone(bw, zone)
+	}
+
+	for _, event := range events {
+		loc, err := eventLocation(event)
+		if err != nil {
+			return err
+		}
+
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, fmt.Sprintf("UID:%d-%d@dev11", event.UserID, event.EventID))
+		writeLine(bw, "DTSTAMP:"+stamp)
+		writeLine(bw, "DTSTART"+formatDateTime(event.Date.Time, loc))
+		if event.End != nil {
+			writeLine(bw, "DTEND"+formatDateTime(event.End.Time, loc))
+		}
+		if event.Title != "" {
+			writeLine(bw, "SUMMARY:"+escapeText(event.Title))
+		}
+		if event.Descr != "" {
+			writeLine(bw, "DESCRIPTION:"+escapeText(event.Descr))
+		}
+		if event.Recurrence != nil {
+			writeLine(bw, "RRULE:"+FormatRRule(event.Recurrence))
+		}
+		for _, exception := range event.Exceptions {
+			writeLine(bw, "EXDATE"+formatDateTime(exception.Time, loc))
+		}
+		writeLine(bw, "END:VEVENT")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+// Skipped - VEVENT of calendar which server can not repeat, it is not imported
+type Skipped struct {
+	// Line - line of END:VEVENT
+	Line    int    `json:"line"`
+	UID     string `json:"uid,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Decode - reads VEVENT components of RFC 5545 calendar, events have no user and id.
+// Events with unsupported rules are returned as skipped, any other wrong event fails the whole calendar
+func Decode(r io.Reader) ([]model.Event, []Skipped, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		events  []model.Event
+		skipped []Skipped
+		props   []property
+		depth   int
+		inside  bool
+	)
+
+	for i, line := range lines {
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			inside, props = true, nil
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if !inside {
+				return nil, nil, fmt.Errorf("line %d: END:VEVENT without BEGIN", i+1)
+			}
+			inside = false
+
+			event, err := buildEvent(props)
+			if errors.Is(err, ErrUnsupported) {
+				skipped = append(skipped, Skipped{
+					Line:    i + 1,
+					UID:     propertyValue(props, "UID"),
+					Summary: unescapeText(propertyValue(props, "SUMMARY")),
+					Reason:  err.Error(),
+				})
+				continue
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("event ending on line %d: %v", i+1, err)
+			}
+			events = append(events, event)
+		case inside && prop.name == "BEGIN":
+			// nested components like VALARM are skipped
+			depth++
+		case inside && prop.name == "END" && depth > 0:
+			depth--
+		case inside && depth == 0:
+			props = append(props, prop)
+		}
+	}
+
+	if inside {
+		return nil, nil, errors.New("VEVENT is not closed")
+	}
+
+	return events, skipped, nil
+}
+
+// property - content line of calendar
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// propertyValue - returns value of the first property with name, empty if there is none
+func propertyValue(props []property, name string) string {
+	for _, prop := range props {
+		if prop.name == name {
+			return prop.value
+		}
+	}
+	return ""
+}
+
+// buildEvent - converts properties of VEVENT into event
+func buildEvent(props []property) (model.Event, error) {
+	var (
+		event    model.Event
+		duration string
+		exdates  []property
+		rrule    string
+	)
+
+	for _, prop := range props {
+		switch prop.name {
+		case "DTSTART":
+			date, tz, err := parseDateProperty(prop)
+			if err != nil {
+				return event, fmt.Errorf("DTSTART: %v", err)
+			}
+			event.Date, event.TZ = model.Date{Time: date}, tz
+		case "DTEND":
+			date, _, err := parseDateProperty(prop)
+			if err != nil {
+				return event, fmt.Errorf("DTEND: %v", err)
+			}
+			event.End = &model.Date{Time: date}
+		case "DURATION":
+			duration = prop.value
+		case "SUMMARY":
+			event.Title = unescapeText(prop.value)
+		case "DESCRIPTION":
+			event.Descr = unescapeText(prop.value)
+		case "RRULE":
+			rrule = prop.value
+		case "EXDATE":
+			exdates = append(exdates, prop)
+		}
+	}
+
+	if event.Date.IsZero() {
+		return event, errors.New("DTSTART is required")
+	}
+
+	loc := event.Date.Location()
+
+	if duration != "" && event.End == nil {
+		d, err := parseDuration(duration)
+		if err != nil {
+			return event, fmt.Errorf("DURATION: %v", err)
+		}
+		if d > 0 {
+			event.End = &model.Date{Time: event.Date.Add(d)}
+		}
+	}
+
+	if rrule != "" {
+		recurrence, err := ParseRRule(rrule, loc)
+		if err != nil {
+			return event, err
+		}
+		event.Recurrence = recurrence
+	}
+
+	for _, prop := range exdates {
+		for _, value := range strings.Split(prop.value, ",") {
+			date, _, err := parseDateProperty(property{name: prop.name, params: prop.params, value: value})
+			if err != nil {
+				return event, fmt.Errorf("EXDATE: %v", err)
+			}
+			event.Exceptions = append(event.Exceptions, model.Date{Time: date.In(loc)})
+		}
+	}
+
+	return event, nil
+}
+
+// eventLocation - returns time zone of event, UTC for event without zone
+func eventLocation(event model.Event) (*time.Location, error) {
+	if event.TZ == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(event.TZ)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q of event %d: %v", event.TZ, event.EventID, err)
+	}
+	return loc, nil
+}
+
+// formatDateTime - returns parameters and value of date property
+func formatDateTime(t time.Time, loc *time.Location) string {
+	if loc == time.UTC {
+		return ":" + t.UTC().Format(utcLayout)
+	}
+	return ";TZID=" + loc.String() + ":" + t.In(loc).Format(localLayout)
+}
+
+// parseDateProperty - parses DATE or DATE-TIME value, returns time zone from TZID parameter
+func parseDateProperty(prop property) (time.Time, string, error) {
+	loc := time.UTC
+	tz := prop.params["TZID"]
+
+	if tz != "" {
+		var err error
+
+		loc, err = time.LoadLocation(strings.TrimPrefix(tz, "/"))
+		if err != nil {
+			return time.Time{}, "", fmt.Errorf("unknown time zone %q", tz)
+		}
+		tz = loc.String()
+	}
+
+	if strings.EqualFold(prop.params["VALUE"], "DATE") {
+		date, err := time.ParseInLocation(dateOnlyLayout, prop.value, loc)
+		return date, tz, err
+	}
+
+	date, err := parseDateTime(prop.value, loc)
+	return date, tz, err
+}
+
+// parseDateTime - parses UTC or floating DATE-TIME, floating one is read in loc
+func parseDateTime(value string, loc *time.Location) (time.Time, error) {
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(utcLayout, value)
+	}
+	if len(value) == len(dateOnlyLayout) {
+		return time.ParseInLocation(dateOnlyLayout, value, loc)
+	}
+	return time.ParseInLocation(localLayout, value, loc)
+}
+
+// durationPattern - RFC 5545 duration like P1DT2H or PT15M or P2W
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration - parses RFC 5545 duration
+func parseDuration(value string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(value)
+	if m == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("wrong duration: %q", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(n) * unit
+	}
+
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// unfold - reads content lines joining folded ones
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// parseLine - splits content line into name, parameters and value
+func parseLine(line string) (property, error) {
+	prop := property{params: make(map[string]string)}
+
+	// value starts after the first colon which is not inside quoted parameter
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return prop, fmt.Errorf("no value in %q", line)
+	}
+
+	prop.value = line[colon+1:]
+
+	parts := strings.Split(line[:colon], ";")
+	prop.name = strings.ToUpper(parts[0])
+
+	for _, param := range parts[1:] {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return prop, fmt.Errorf("wrong parameter %q", param)
+		}
+		prop.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+
+	return prop, nil
+}
+
+// writeLine - writes content line folding it by 75 octets without splitting UTF-8 characters
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineLength
+
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+
+		// continuation lines start with space
+		limit = maxLineLength - 1
+	}
+
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+// textEscaper - escapes special characters of TEXT value
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escapeText - escapes TEXT value
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// unescapeText - restores TEXT value
+func unescapeText(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"main.go/internal/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatRRule - returns recurrence as value of RFC 5545 RRULE property
+func FormatRRule(r *model.Recurrence) string {
+	parts := []string{"FREQ=" + r.Freq}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(utcLayout))
+	}
+	if len(r.ByDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.ToUpper(strings.Join(r.ByDay, ",")))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// ErrUnsupported - rule is valid, but server can not repeat event by it
+var ErrUnsupported = errors.New("unsupported")
+
+// weekdays - RFC 5545 codes of week days
+var weekdays = map[string]bool{"MO": true, "TU": true, "WE": true, "TH": true, "FR": true, "SA": true, "SU": true}
+
+// ParseRRule - parses value of RFC 5545 RRULE property, floating UNTIL is read in loc.
+// Only FREQ, INTERVAL, COUNT, UNTIL and BYDAY parts are supported, WKST is accepted while it does not
+// change occurrences. Other rules give error wrapping ErrUnsupported
+func ParseRRule(value string, loc *time.Location) (*model.Recurrence, error) {
+	var (
+		r    model.Recurrence
+		wkst = "MO"
+	)
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("wrong RRULE part: %q", part)
+		}
+
+		var err error
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(val)
+			switch r.Freq {
+			case model.Daily, model.Weekly, model.Monthly, model.Yearly:
+			default:
+				err = fmt.Errorf("%w frequency", ErrUnsupported)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(val)
+		case "COUNT":
+			r.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			var until time.Time
+			until, err = parseDateTime(val, loc)
+			r.Until = &model.Date{Time: until}
+		case "BYDAY":
+			r.ByDay = strings.Split(strings.ToUpper(val), ",")
+		case "WKST":
+			wkst = strings.ToUpper(val)
+			if !weekdays[wkst] {
+				err = errors.New("unknown week day")
+			}
+		default:
+			return nil, fmt.Errorf("%w RRULE part: %q", ErrUnsupported, part)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("wrong RRULE part %q: %v", part, err)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("RRULE without FREQ: %q", value)
+	}
+
+	// weeks always start on Monday, other start changes only weeks of several days repeated with interval
+	if wkst != "MO" && r.Freq == model.Weekly && r.Interval > 1 && len(r.ByDay) > 1 {
+		return nil, fmt.Errorf("%w RRULE part: %q, weeks start on Monday", ErrUnsupported, "WKST="+wkst)
+	}
+
+	return &r, nil
+}
@@ -0,0 +1,125 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"main.go/internal/model"
+	"time"
+)
+
+// openEndedYears - how many years after start of endless recurrence are described by VTIMEZONE
+const openEndedYears = 5
+
+// zoneSpan - time zone used by events and period of their dates in it
+type zoneSpan struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// add - extends period of zone so that it contains t
+func (z *zoneSpan) add(t time.Time) {
+	if z.from.IsZero() || t.Before(z.from) {
+		z.from = t
+	}
+	if t.After(z.to) {
+		z.to = t
+	}
+}
+
+// usedZones - returns zones named by TZID in calendar with periods of their events, in order of first use
+func usedZones(events []model.Event) ([]*zoneSpan, error) {
+	var zones []*zoneSpan
+	byName := make(map[string]*zoneSpan)
+
+	for _, event := range events {
+		loc, err := eventLocation(event)
+		if err != nil {
+			return nil, err
+		}
+		if loc == time.UTC {
+			continue
+		}
+
+		zone, ok := byName[loc.String()]
+		if !ok {
+			zone = &zoneSpan{loc: loc}
+			byName[loc.String()] = zone
+			zones = append(zones, zone)
+		}
+
+		zone.add(event.Date.Time)
+		if event.End != nil {
+			zone.add(event.End.Time)
+		}
+		for _, exception := range event.Exceptions {
+			zone.add(exception.Time)
+		}
+		if event.Recurrence != nil {
+			if event.Recurrence.Until != nil {
+				zone.add(event.Recurrence.Until.Time)
+			} else {
+				zone.add(event.Date.AddDate(openEndedYears, 0, 0))
+			}
+		}
+	}
+
+	return zones, nil
+}
+
+// writeTimezone - writes VTIMEZONE with observances of zone from the start of the first year
+// of its period till the end of the last one, so every TZID of calendar has its definition
+func writeTimezone(bw *bufio.Writer, zone *zoneSpan) {
+	from := time.Date(zone.from.In(zone.loc).Year(), 1, 1, 0, 0, 0, 0, zone.loc)
+	to := time.Date(zone.to.In(zone.loc).Year()+1, 1, 1, 0, 0, 0, 0, zone.loc)
+
+	writeLine(bw, "BEGIN:VTIMEZONE")
+	writeLine(bw, "TZID:"+zone.loc.String())
+
+	// the first observance describes offset in force at the start of period
+	_, offset := from.Zone()
+	writeObservance(bw, from, offset)
+
+	for t := from; ; {
+		_, end := t.ZoneBounds()
+		if end.IsZero() || !end.Before(to) {
+			break
+		}
+		writeObservance(bw, end, offset)
+		_, offset = end.Zone()
+		t = end
+	}
+
+	writeLine(bw, "END:VTIMEZONE")
+}
+
+// writeObservance - writes STANDARD or DAYLIGHT component of offset starting at t,
+// DTSTART of observance is local time of the previous offset
+func writeObservance(bw *bufio.Writer, t time.Time, previous int) {
+	name, offset := t.Zone()
+
+	component := "STANDARD"
+	if t.IsDST() {
+		component = "DAYLIGHT"
+	}
+
+	writeLine(bw, "BEGIN:"+component)
+	writeLine(bw, "DTSTART:"+t.In(time.FixedZone("", previous)).Format(localLayout))
+	writeLine(bw, "TZOFFSETFROM:"+formatOffset(previous))
+	writeLine(bw, "TZOFFSETTO:"+formatOffset(offset))
+	writeLine(bw, "TZNAME:"+escapeText(name))
+	writeLine(bw, "END:"+component)
+}
+
+// formatOffset - returns UTC offset in seconds as +HHMM or +HHMMSS
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+
+	hours, minutes, seconds := offset/3600, offset/60%60, offset%60
+	if seconds != 0 {
+		return fmt.Sprintf("%c%02d%02d%02d", sign, hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, hours, minutes)
+}
@@ -0,0 +1,260 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// contentType - Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets - upper bounds of latency histogram in seconds
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector - metric family which can be written in text format
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry - set of metrics exposed together
+type Registry struct {
+	sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry - creates empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// ServeHTTP - responds with all metrics in Prometheus text format
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_ = reg.Write(w)
+}
+
+// Write - writes all metrics in Prometheus text format
+func (reg *Registry) Write(w io.Writer) error {
+	reg.Lock()
+	collectors := append([]collector(nil), reg.collectors...)
+	reg.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// register - adds metric family to registry
+func (reg *Registry) register(c collector) {
+	reg.Lock()
+	reg.collectors = append(reg.collectors, c)
+	reg.Unlock()
+}
+
+// desc - name, help and label names of metric family
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// header - writes HELP and TYPE lines
+func (d desc) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, kind)
+}
+
+// key - joins label values into key of series
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs - returns label set like {a="1",b="2"} for key of series, extra pair is added at the end
+func (d desc) labelPairs(key string, extra ...string) string {
+	var pairs []string
+
+	if len(d.labels) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, d.labels[i]+`="`+escapeLabel(value)+`"`)
+		}
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, extra[0]+`="`+escapeLabel(extra[1])+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec - counters partitioned by labels
+type CounterVec struct {
+	desc
+	sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec - creates counter family and registers it
+func (reg *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]float64)}
+	reg.register(c)
+	return c
+}
+
+// Inc - adds one to counter with label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	key := c.key(labelValues)
+
+	c.Lock()
+	c.values[key]++
+	c.Unlock()
+}
+
+// write - writes counters sorted by labels
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.header(w, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(key), formatFloat(c.values[key]))
+	}
+}
+
+// HistogramVec - histograms partitioned by labels
+type HistogramVec struct {
+	desc
+	sync.Mutex
+	buckets []float64
+	series  map[string]*histogram
+}
+
+// histogram - observations of one series, counts are not cumulative
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec - creates histogram family with sorted upper bounds of buckets and registers it
+func (reg *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogram),
+	}
+	reg.register(h)
+	return h
+}
+
+// Observe - adds value to histogram with label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.Lock()
+	defer h.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	// value goes to the first bucket which holds it, buckets are summed on write
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += value
+	s.count++
+}
+
+// write - writes cumulative buckets, sum and count of every series
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.header(w, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(key, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(key, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(key), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(key), s.count)
+	}
+}
+
+// GaugeFunc - gauge which value is read on every scrape
+type GaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+// NewGaugeFunc - creates gauge reading its value from fn and registers it
+func (reg *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name: name, help: help}, fn: fn}
+	reg.register(g)
+	return g
+}
+
+// write - writes current value
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.header(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+// sortedKeys - returns keys of series in stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatFloat - formats sample value like Prometheus does
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// labelEscaper - escapes backslash, quote and new line in label value
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel - escapes label value
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// helpEscaper - escapes backslash and new line in help text
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// escapeHelp - escapes help text
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
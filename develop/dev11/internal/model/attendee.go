@@ -0,0 +1,61 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Answers of attendee, names are the same as RFC 5545 PARTSTAT values
+const (
+	NeedsAction = "needs-action"
+	Accepted    = "accepted"
+	Declined    = "declined"
+	Tentative   = "tentative"
+)
+
+// Attendee - user invited to event
+type Attendee struct {
+	UserID int `json:"user_id"`
+	// Status - answer of attendee, only attendee can change it
+	Status string `json:"status,omitempty"`
+}
+
+// ValidStatus - checks if status is known answer of attendee
+func ValidStatus(status string) bool {
+	switch status {
+	case NeedsAction, Accepted, Declined, Tentative:
+		return true
+	}
+	return false
+}
+
+// validateAttendees - checks that attendees are other users listed once
+func (e *Event) validateAttendees() error {
+	seen := make(map[int]bool, len(e.Attendees))
+
+	for _, attendee := range e.Attendees {
+		switch {
+		case attendee.UserID < 1:
+			return errors.New("userID of attendee should be positive")
+		case attendee.UserID == e.UserID:
+			return errors.New("owner can not be attendee of own event")
+		case seen[attendee.UserID]:
+			return fmt.Errorf("user %d is invited twice", attendee.UserID)
+		case attendee.Status != "" && !ValidStatus(attendee.Status):
+			return fmt.Errorf("unknown status %q of user %d", attendee.Status, attendee.UserID)
+		}
+		seen[attendee.UserID] = true
+	}
+
+	return nil
+}
+
+// Attendee - returns attendee of event with user id
+func (e *Event) Attendee(userID int) (Attendee, bool) {
+	for _, attendee := range e.Attendees {
+		if attendee.UserID == userID {
+			return attendee, true
+		}
+	}
+	return Attendee{}, false
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Actions of audit records
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionRespond = "respond"
+)
+
+// AuditRecord - single change of event in its history
+type AuditRecord struct {
+	UserID  int    `json:"user_id"`
+	EventID int    `json:"event_id"`
+	Action  string `json:"action"`
+	// ActorID - user who made the change, owner or attendee who answered
+	ActorID int       `json:"actor_id"`
+	At      time.Time `json:"at"`
+	// Before - event before the change, nil for creation
+	Before *Event `json:"before,omitempty"`
+	// After - event after the change, nil for deletion
+	After *Event `json:"after,omitempty"`
+}
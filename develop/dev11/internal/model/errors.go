@@ -0,0 +1,61 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotApplied - operation of atomic transaction is rolled back because another operation failed
+var ErrNotApplied = errors.New("operation is not applied, another operation of transaction failed")
+
+// NotFoundError - event is missing
+type NotFoundError struct {
+	UserID  int
+	EventID int
+}
+
+// Error - returns text of error
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("there is no event %d of user %d", e.EventID, e.UserID)
+}
+
+// VersionConflictError - event was changed after the version client has seen
+type VersionConflictError struct {
+	UserID  int
+	EventID int
+	// Version - current version of event
+	Version int
+}
+
+// Error - returns text of error
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("event %d of user %d is changed, current version is %d", e.EventID, e.UserID, e.Version)
+}
+
+// QuotaError - user already has as many events as allowed
+type QuotaError struct {
+	UserID int
+	Limit  int
+}
+
+// Error - returns text of error
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("user %d has reached the limit of %d events", e.UserID, e.Limit)
+}
+
+// TransactionError - failed operation which rolled back atomic transaction
+type TransactionError struct {
+	// Index - position of operation in transaction
+	Index int
+	Err   error
+}
+
+// Error - returns text of error
+func (e *TransactionError) Error() string {
+	return fmt.Sprintf("operation %d failed: %v", e.Index, e.Err)
+}
+
+// Unwrap - returns error of operation
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
@@ -1,9 +1,13 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
+
+	// time zones are available even without system database
+	_ "time/tzdata"
 )
 
 // Event - store all information about event
@@ -13,31 +17,216 @@ type Event struct {
 	Title   string `json:"title"`
 	Descr   string `json:"descr"`
 	Date    Date   `json:"date"`
+	// TZ - IANA time zone of event, dates without offset are read in it and recurrence follows its clock
+	TZ string `json:"tz,omitempty"`
+	// End - end of event, for recurring event - end of the first occurrence. Event without end takes no time
+	End *Date `json:"end,omitempty"`
+
+	// Recurrence - rule of repeating, nil for single event
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// Exceptions - starts of occurrences removed from recurring event
+	Exceptions []Date `json:"exceptions,omitempty"`
+	// SeriesDate - date of the first occurrence, set only in occurrences returned by queries
+	SeriesDate *Date `json:"series_date,omitempty"`
+
+	// Attendees - invited users with their answers, event is shown in their calendars too
+	Attendees []Attendee `json:"attendees,omitempty"`
+	// Reminders - notifications sent before every occurrence
+	Reminders []Reminder `json:"reminders,omitempty"`
+
+	// Version - number of the stored state of event, it grows with every change.
+	// Update and delete name the version they change, so concurrent changes are not lost
+	Version int `json:"version,omitempty"`
+}
+
+// UnmarshalJSON - decodes event and moves its dates to its time zone
+func (e *Event) UnmarshalJSON(b []byte) error {
+	// plain - event without custom unmarshal
+	type plain Event
+
+	err := json.Unmarshal(b, (*plain)(e))
+	if err != nil {
+		return err
+	}
+
+	return e.Localize()
+}
+
+// Localize - moves dates of event to its time zone, dates without offset are read as wall clock in that zone
+func (e *Event) Localize() error {
+	if e.TZ == "" {
+		return nil
+	}
+
+	loc, err := time.LoadLocation(e.TZ)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q: %v", e.TZ, err)
+	}
+
+	e.Date = e.Date.in(loc)
+
+	if e.End != nil {
+		end := e.End.in(loc)
+		e.End = &end
+	}
+
+	if e.SeriesDate != nil {
+		seriesDate := e.SeriesDate.in(loc)
+		e.SeriesDate = &seriesDate
+	}
+
+	if e.Recurrence != nil && e.Recurrence.Until != nil {
+		recurrence := *e.Recurrence
+		until := recurrence.Until.in(loc)
+		recurrence.Until = &until
+		e.Recurrence = &recurrence
+	}
+
+	for i := range e.Exceptions {
+		e.Exceptions[i] = e.Exceptions[i].in(loc)
+	}
+
+	return nil
+}
+
+// Validate - checks business rules of event, problems are returned as *ValidationError
+func (e *Event) Validate() error {
+	var verr ValidationError
+
+	if e.Date.IsZero() {
+		verr.Add("date", "date is required")
+	}
+
+	if e.End != nil && !e.End.After(e.Date.Time) {
+		verr.Add("end", "end of event should be after its start")
+	}
+
+	if e.Recurrence == nil {
+		if len(e.Exceptions) > 0 {
+			verr.Add("exceptions", "exceptions are allowed only for recurring event")
+		}
+	} else if err := e.Recurrence.Validate(e.Date.Time); err != nil {
+		verr.Add("recurrence", err.Error())
+	}
+
+	if err := e.validateAttendees(); err != nil {
+		verr.Add("attendees", err.Error())
+	}
+
+	if err := e.validateReminders(); err != nil {
+		verr.Add("reminders", err.Error())
+	}
+
+	return verr.Err()
+}
+
+// Duration - returns duration of every occurrence of event
+func (e Event) Duration() time.Duration {
+	if e.End == nil {
+		return 0
+	}
+	return e.End.Sub(e.Date.Time)
+}
+
+// Overlaps - checks if two time intervals intersect, intervals without duration intersect nothing
+func Overlaps(aStart, aEnd, bStart, bEnd time.Time) bool {
+	if !aEnd.After(aStart) || !bEnd.After(bStart) {
+		return false
+	}
+	return aStart.Before(bEnd) && bStart.Before(aEnd)
 }
 
 // Date - custom date type
 type Date struct {
 	time.Time
+	// floating - date was given without offset, so it is wall clock in time zone of event
+	floating bool
 }
 
-// UnmarshalJSON - custom unmarshal
+// dateLayout - accepted format of date
+type dateLayout struct {
+	layout   string
+	floating bool
+}
+
+// dateLayouts - accepted formats of date, RFC 3339 goes first as the format of marshaled time.Time
+var dateLayouts = []dateLayout{
+	{layout: time.RFC3339Nano},
+	{layout: "2006-01-02T15:04", floating: true},
+	{layout: "2006-01-02T15:04:05", floating: true},
+	{layout: "2006-01-02", floating: true},
+}
+
+// UnmarshalJSON - custom unmarshal, empty date stays zero and is rejected by Validate of event
 func (t *Date) UnmarshalJSON(b []byte) error {
-	if string(b) == "" || string(b) == `""` {
-		*t = Date{time.Now()}
+	if string(b) == "" || string(b) == `""` || string(b) == "null" {
+		*t = Date{}
 		return nil
 	}
 
 	timeStr := strings.ReplaceAll(string(b), `"`, "")
-	parsedTime, err := time.Parse("2006-01-02T15:04", timeStr)
+
+	parsed, err := parseDate(timeStr, time.UTC)
 	if err != nil {
-		parsedTime, err = time.Parse("2006-01-02T15:04:00Z", timeStr)
-		if err != nil {
-			parsedTime, err = time.Parse("2006-01-02", timeStr)
-			if err != nil {
-				return fmt.Errorf("date format: e.g. 2022-05-10T14:10 error: %v", err)
-			}
-		}
+		return err
 	}
-	*t = Date{parsedTime}
+
+	*t = parsed
 	return nil
 }
+
+// ParseDate - parses date, date without offset is read in loc
+func ParseDate(s string, loc *time.Location) (time.Time, error) {
+	date, err := parseDate(s, loc)
+	return date.Time, err
+}
+
+// parseDate - parses date trying all accepted layouts
+func parseDate(s string, loc *time.Location) (Date, error) {
+	var err error
+
+	for _, l := range dateLayouts {
+		var parsed time.Time
+
+		parsed, err = time.ParseInLocation(l.layout, s, loc)
+		if err == nil {
+			return Date{Time: parsed, floating: l.floating}, nil
+		}
+	}
+
+	return Date{}, fmt.Errorf("date format: e.g. 2022-05-10T14:10 or 2022-05-10T14:10:00+03:00 error: %v", err)
+}
+
+// in - moves date to time zone, floating date keeps its wall clock
+func (t Date) in(loc *time.Location) Date {
+	if !t.floating {
+		return Date{Time: t.In(loc)}
+	}
+
+	y, m, d := t.Date()
+	hh, mm, ss := t.Clock()
+
+	return Date{Time: time.Date(y, m, d, hh, mm, ss, t.Nanosecond(), loc)}
+}
+
+// Cursor - position in list of occurrences sorted by date, event id and owner id, zero cursor is before all of them.
+// Event ids are unique only for owner, so list with invitations needs owner to tell equal ids apart
+type Cursor struct {
+	Date    time.Time
+	EventID int
+	UserID  int
+}
+
+// Before - checks if cursor goes before occurrence of event
+func (c Cursor) Before(date time.Time, eventID, userID int) bool {
+	if c.Date.IsZero() && c.EventID == 0 && c.UserID == 0 {
+		return true
+	}
+	if !c.Date.Equal(date) {
+		return c.Date.Before(date)
+	}
+	if c.EventID != eventID {
+		return c.EventID < eventID
+	}
+	return c.UserID < userID
+}
@@ -0,0 +1,220 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval - period of time [Start, End)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// MergeIntervals - returns sorted intervals where overlapping and adjacent ones are joined
+func MergeIntervals(intervals []Interval) []Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	sorted := append([]Interval(nil), intervals...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	merged := []Interval{sorted[0]}
+	for _, next := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if next.Start.After(last.End) {
+			merged = append(merged, next)
+			continue
+		}
+		if next.End.After(last.End) {
+			last.End = next.End
+		}
+	}
+
+	return merged
+}
+
+// WorkingHours - daily time when user can meet: from Start to End minutes of day on Days in Location
+type WorkingHours struct {
+	Start    int
+	End      int
+	Days     []time.Weekday
+	Location *time.Location
+}
+
+// DefaultWorkingHours - Monday to Friday from 9:00 to 18:00 UTC
+func DefaultWorkingHours() WorkingHours {
+	return WorkingHours{
+		Start:    9 * 60,
+		End:      18 * 60,
+		Days:     []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Location: time.UTC,
+	}
+}
+
+// dayNames - names of days in working hours
+var dayNames = map[string]time.Weekday{
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+	"sun": time.Sunday,
+}
+
+// ParseWorkingHours - parses wall clock start and end like 09:30, days like mon and IANA time zone.
+// Empty days mean Monday to Friday, empty time zone is UTC
+func ParseWorkingHours(start, end string, days []string, tz string) (WorkingHours, error) {
+	hours := DefaultWorkingHours()
+
+	var err error
+
+	hours.Start, err = parseClock(start)
+	if err != nil {
+		return WorkingHours{}, err
+	}
+
+	hours.End, err = parseClock(end)
+	if err != nil {
+		return WorkingHours{}, err
+	}
+
+	if hours.End <= hours.Start {
+		return WorkingHours{}, fmt.Errorf("end of working hours %s should be after start %s", end, start)
+	}
+
+	if len(days) > 0 {
+		hours.Days = nil
+		for _, day := range days {
+			weekday, ok := dayNames[strings.ToLower(day)]
+			if !ok {
+				return WorkingHours{}, fmt.Errorf("unknown day %q, should be mon, tue, wed, thu, fri, sat or sun", day)
+			}
+			hours.Days = append(hours.Days, weekday)
+		}
+	}
+
+	if tz != "" {
+		hours.Location, err = time.LoadLocation(tz)
+		if err != nil {
+			return WorkingHours{}, fmt.Errorf("unknown time zone %q: %v", tz, err)
+		}
+	}
+
+	return hours, nil
+}
+
+// parseClock - returns minutes of day from wall clock like 09:30, 24:00 is the end of day
+func parseClock(s string) (int, error) {
+	errClock := fmt.Errorf("wrong time of day %q, should be like 09:30", s)
+
+	h, m, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, errClock
+	}
+
+	hour, err := strconv.Atoi(h)
+	if err != nil {
+		return 0, errClock
+	}
+	minute, err := strconv.Atoi(m)
+	if err != nil || len(m) != 2 {
+		return 0, errClock
+	}
+
+	minutes := hour*60 + minute
+	if hour < 0 || minute < 0 || minute > 59 || minutes > 24*60 {
+		return 0, errClock
+	}
+
+	return minutes, nil
+}
+
+// Windows - returns working intervals which overlap [from, to), cut to it
+func (w WorkingHours) Windows(from, to time.Time) []Interval {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var windows []Interval
+
+	local := from.In(loc)
+	for day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !w.worksOn(day.Weekday()) {
+			continue
+		}
+
+		// wall clock is kept on days when clocks are changed
+		y, m, d := day.Date()
+		start := time.Date(y, m, d, 0, w.Start, 0, 0, loc)
+		end := time.Date(y, m, d, 0, w.End, 0, 0, loc)
+
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if start.Before(end) {
+			windows = append(windows, Interval{Start: start, End: end})
+		}
+	}
+
+	return windows
+}
+
+// worksOn - checks if weekday is working day
+func (w WorkingHours) worksOn(weekday time.Weekday) bool {
+	for _, day := range w.Days {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// FindSlot - returns the earliest interval of duration in [from, to) which is inside working hours
+// of every user and does not overlap their busy intervals. busy and hours go in the same order of users
+func FindSlot(from, to time.Time, duration time.Duration, busy [][]Interval, hours []WorkingHours) (Interval, bool) {
+	var blocked []Interval
+
+	for i := range hours {
+		blocked = append(blocked, busy[i]...)
+
+		// time out of working hours is blocked
+		cursor := from
+		for _, window := range hours[i].Windows(from, to) {
+			if cursor.Before(window.Start) {
+				blocked = append(blocked, Interval{Start: cursor, End: window.Start})
+			}
+			cursor = window.End
+		}
+		if cursor.Before(to) {
+			blocked = append(blocked, Interval{Start: cursor, End: to})
+		}
+	}
+
+	cursor := from
+	for _, interval := range MergeIntervals(blocked) {
+		if !interval.Start.Before(cursor.Add(duration)) {
+			break
+		}
+		if interval.End.After(cursor) {
+			cursor = interval.End
+		}
+	}
+
+	if cursor.Add(duration).After(to) {
+		return Interval{}, false
+	}
+
+	return Interval{Start: cursor, End: cursor.Add(duration)}, true
+}
@@ -0,0 +1,21 @@
+package model
+
+// Kinds of operations of batch
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Operation - single change of batch. Deletion needs only ids and version of event
+type Operation struct {
+	Op    string `json:"op"`
+	Event Event  `json:"event"`
+}
+
+// OperationResult - outcome of operation of batch, Err is nil if operation is applied
+type OperationResult struct {
+	// Event - stored event with assigned id and version, for deletion - deleted event
+	Event Event
+	Err   error
+}
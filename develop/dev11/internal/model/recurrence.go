@@ -0,0 +1,348 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies of recurrence, names are the same as in RFC 5545 RRULE
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// maxPeriods - limit of periods checked while expanding recurrence, protects from rules which never match
+const maxPeriods = 100000
+
+// weekdays - RFC 5545 names of week days
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence - rule of repeating event, subset of RFC 5545 RRULE
+type Recurrence struct {
+	// Freq - DAILY, WEEKLY, MONTHLY or YEARLY
+	Freq string `json:"freq"`
+	// Interval - event repeats every Interval periods, 1 if omitted
+	Interval int `json:"interval,omitempty"`
+	// Count - number of occurrences, unlimited if omitted
+	Count int `json:"count,omitempty"`
+	// Until - last possible start of occurrence
+	Until *Date `json:"until,omitempty"`
+	// ByDay - week days like MO or FR, for MONTHLY and YEARLY may have position in period like 1MO or -1FR
+	ByDay []string `json:"by_day,omitempty"`
+}
+
+// byDay - parsed BYDAY element
+type byDay struct {
+	weekday time.Weekday
+	// pos - position of week day in month or year, 0 means every such day
+	pos int
+}
+
+// Validate - checks that rule can be expanded from start
+func (r *Recurrence) Validate(start time.Time) error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	default:
+		return fmt.Errorf("unknown recurrence frequency: %q", r.Freq)
+	}
+
+	if r.Interval < 0 || r.Count < 0 {
+		return errors.New("recurrence interval and count should not be negative")
+	}
+
+	if r.Count > 0 && r.Until != nil {
+		return errors.New("recurrence can not have both count and until")
+	}
+
+	if r.Until != nil && r.Until.Before(start) {
+		return errors.New("recurrence until should not be before event date")
+	}
+
+	_, err := r.parseByDay()
+	return err
+}
+
+// parseByDay - parses BYDAY elements. Position is limited by number of such week days in period:
+// up to 5 in month and up to 53 in year
+func (r *Recurrence) parseByDay() ([]byDay, error) {
+	days := make([]byDay, 0, len(r.ByDay))
+	seen := make(map[byDay]bool, len(r.ByDay))
+
+	maxPos := 5
+	if r.Freq == Yearly {
+		maxPos = 53
+	}
+
+	for _, s := range r.ByDay {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if len(s) < 2 {
+			return nil, fmt.Errorf("wrong week day: %q", s)
+		}
+
+		weekday, ok := weekdays[s[len(s)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("wrong week day: %q", s)
+		}
+
+		var pos int
+		if prefix := s[:len(s)-2]; prefix != "" {
+			if r.Freq != Monthly && r.Freq != Yearly {
+				return nil, fmt.Errorf("week day position is allowed only for monthly and yearly recurrence: %q", s)
+			}
+
+			var err error
+			pos, err = strconv.Atoi(prefix)
+			if err != nil || pos == 0 || pos < -maxPos || pos > maxPos {
+				return nil, fmt.Errorf("wrong week day position: %q", s)
+			}
+		}
+
+		day := byDay{weekday: weekday, pos: pos}
+		if seen[day] {
+			return nil, fmt.Errorf("repeated week day: %q", s)
+		}
+		seen[day] = true
+
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+// Occurrences - returns starts of event occurrences which overlap [from, to), exceptions are skipped.
+// Occurrence without duration overlaps window if it starts in it
+func (e Event) Occurrences(from, to time.Time) []time.Time {
+	start := e.Date.Time
+
+	// occurrence started before window may still last in it
+	if duration := e.Duration(); duration > 0 {
+		from = from.Add(-duration + time.Nanosecond)
+	}
+
+	if e.Recurrence == nil {
+		if !start.Before(from) && start.Before(to) {
+			return []time.Time{start}
+		}
+		return nil
+	}
+
+	r := e.Recurrence
+	days, err := r.parseByDay()
+	if err != nil {
+		return nil
+	}
+
+	interval := r.Interval
+	if interval == 0 {
+		interval = 1
+	}
+
+	var (
+		occurrences []time.Time
+		n           int
+	)
+
+	// without count nothing before window has to be counted, so expansion starts near it
+	period := 0
+	if r.Count == 0 {
+		period = r.periodsBefore(start, from) / interval
+	}
+
+	for ; period < maxPeriods; period++ {
+		for _, occurrence := range r.candidates(start, period*interval, days) {
+			if occurrence.Before(start) {
+				continue
+			}
+
+			n++
+			if r.Count > 0 && n > r.Count {
+				return occurrences
+			}
+			if r.Until != nil && occurrence.After(r.Until.Time) {
+				return occurrences
+			}
+			if !occurrence.Before(to) {
+				return occurrences
+			}
+
+			if !occurrence.Before(from) && !e.isException(occurrence) {
+				occurrences = append(occurrences, occurrence)
+			}
+		}
+	}
+
+	return occurrences
+}
+
+// isException - checks if occurrence is excluded. Exception without time excludes the whole day
+func (e Event) isException(occurrence time.Time) bool {
+	for _, exception := range e.Exceptions {
+		if exception.Equal(occurrence) {
+			return true
+		}
+
+		h, m, s := exception.Clock()
+		if h == 0 && m == 0 && s == 0 && exception.Nanosecond() == 0 {
+			y, mon, d := exception.Date()
+			oy, omon, od := occurrence.Date()
+			if y == oy && mon == omon && d == od {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// periodsBefore - returns number of whole periods between start and t
+func (r *Recurrence) periodsBefore(start, t time.Time) int {
+	if !t.After(start) {
+		return 0
+	}
+
+	var periods int
+
+	switch r.Freq {
+	case Daily:
+		periods = int(t.Sub(start).Hours() / 24)
+	case Weekly:
+		periods = int(t.Sub(start).Hours() / (24 * 7))
+	case Monthly:
+		periods = (t.Year()-start.Year())*12 + int(t.Month()-start.Month())
+	case Yearly:
+		periods = t.Year() - start.Year()
+	}
+
+	// one period reserve for days with daylight saving shifts and BYDAY before start day
+	if periods > 0 {
+		periods--
+	}
+	return periods
+}
+
+// candidates - returns sorted starts of occurrences in period which is shift periods after start
+func (r *Recurrence) candidates(start time.Time, shift int, days []byDay) []time.Time {
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	ns := start.Nanosecond()
+	loc := start.Location()
+
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, ns, loc)
+	}
+
+	var result []time.Time
+
+	switch r.Freq {
+	case Daily:
+		day := at(y, m, d+shift)
+		if len(days) == 0 || hasWeekday(days, day.Weekday()) {
+			result = append(result, day)
+		}
+
+	case Weekly:
+		if len(days) == 0 {
+			return []time.Time{at(y, m, d+7*shift)}
+		}
+		// weeks start on Monday
+		monday := d - (int(start.Weekday())+6)%7 + 7*shift
+		for _, day := range days {
+			result = append(result, at(y, m, monday+(int(day.weekday)+6)%7))
+		}
+
+	case Monthly:
+		first := time.Date(y, m+time.Month(shift), 1, 0, 0, 0, 0, loc)
+		if len(days) == 0 {
+			// months without such day are skipped
+			if d <= daysIn(first.Year(), first.Month()) {
+				result = append(result, at(first.Year(), first.Month(), d))
+			}
+			break
+		}
+		for _, day := range matchDays(first.Year(), first.Month(), 1, daysIn(first.Year(), first.Month()), days) {
+			result = append(result, at(first.Year(), first.Month(), day))
+		}
+
+	case Yearly:
+		year := y + shift
+		if len(days) == 0 {
+			// February 29 happens only in leap years
+			if d <= daysIn(year, m) {
+				result = append(result, at(year, m, d))
+			}
+			break
+		}
+		// days of year are counted from January 1, time.Date normalizes them
+		for _, day := range matchDays(year, time.January, 1, daysInYear(year), days) {
+			result = append(result, at(year, time.January, day))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Before(result[j])
+	})
+
+	return result
+}
+
+// matchDays - returns days from first to last counted from the first day of month which match BYDAY
+func matchDays(y int, m time.Month, first, last int, days []byDay) []int {
+	var result []int
+
+	firstWeekday := time.Date(y, m, first, 0, 0, 0, 0, time.UTC).Weekday()
+
+	for _, day := range days {
+		// first such week day in period
+		firstDay := first + (int(day.weekday)-int(firstWeekday)+7)%7
+		switch {
+		case day.pos == 0:
+			for d := firstDay; d <= last; d += 7 {
+				result = append(result, d)
+			}
+		case day.pos > 0:
+			if d := firstDay + 7*(day.pos-1); d <= last {
+				result = append(result, d)
+			}
+		default:
+			lastDay := firstDay + (last-firstDay)/7*7
+			if d := lastDay + 7*(day.pos+1); d >= first {
+				result = append(result, d)
+			}
+		}
+	}
+
+	return result
+}
+
+// hasWeekday - checks if BYDAY contains week day
+func hasWeekday(days []byDay, weekday time.Weekday) bool {
+	for _, day := range days {
+		if day.weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn - returns number of days in month
+func daysIn(y int, m time.Month) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// daysInYear - returns number of days in year
+func daysInYear(y int) int {
+	return time.Date(y, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
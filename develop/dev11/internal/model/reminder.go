@@ -0,0 +1,37 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxReminderLead - the earliest reminder goes this long before occurrence
+const MaxReminderLead = 4 * 7 * 24 * time.Hour
+
+// Reminder - notification about occurrence of event
+type Reminder struct {
+	// MinutesBefore - how long before start of occurrence reminder fires, 0 fires at start
+	MinutesBefore int `json:"minutes_before"`
+}
+
+// Lead - returns time between reminder and start of occurrence
+func (r Reminder) Lead() time.Duration {
+	return time.Duration(r.MinutesBefore) * time.Minute
+}
+
+// validateReminders - checks that reminders fit in MaxReminderLead and are not repeated
+func (e *Event) validateReminders() error {
+	seen := make(map[int]bool, len(e.Reminders))
+
+	for _, reminder := range e.Reminders {
+		if reminder.MinutesBefore < 0 || reminder.Lead() > MaxReminderLead {
+			return fmt.Errorf("reminder should be from 0 to %d minutes before event", int(MaxReminderLead.Minutes()))
+		}
+		if seen[reminder.MinutesBefore] {
+			return fmt.Errorf("reminder %d minutes before event is repeated", reminder.MinutesBefore)
+		}
+		seen[reminder.MinutesBefore] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package model
+
+import "iter"
+
+// Rules - business rules of stored events. Data store checks them under its lock,
+// so no other change comes between check and write
+type Rules interface {
+	// CheckPut - checks event before it is stored. Added is true for created or restored event
+	// and false for replaced one, stored - count events of its owner already in data store
+	CheckPut(event Event, stored iter.Seq[Event], count int, added bool) error
+	// TrashLimit - how many latest deleted events of one user are kept, zero means no limit
+	TrashLimit() int
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Terms - splits text into lower case words without repeats, punctuation separates words
+func Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]struct{}, len(words))
+	terms := words[:0]
+	for _, word := range words {
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		terms = append(terms, word)
+	}
+
+	return terms
+}
+
+// Terms - returns words of title and description of event
+func (e Event) Terms() []string {
+	return Terms(e.Title + " " + e.Descr)
+}
+
+// MatchesTerms - checks that every query term is prefix of some word of event
+func (e Event) MatchesTerms(query []string) bool {
+	words := e.Terms()
+
+	for _, term := range query {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"sort"
+	"strings"
+)
+
+// ValidationError - wrong fields of input, key is field name and value is description of problem
+type ValidationError struct {
+	Fields map[string]string
+}
+
+// Error - returns all problems sorted by field name
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := make([]string, 0, len(names))
+	for _, name := range names {
+		problems = append(problems, name+": "+e.Fields[name])
+	}
+
+	return strings.Join(problems, "; ")
+}
+
+// Add - remembers problem of field, only the first problem of each field is kept
+func (e *ValidationError) Add(field, problem string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string]string)
+	}
+	if _, ok := e.Fields[field]; !ok {
+		e.Fields[field] = problem
+	}
+}
+
+// Err - returns nil if there are no problems
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,373 @@
+package reminder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"main.go/internal/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Source - data store with events
+type Source interface {
+	GetOccurrencesStarting(from, to time.Time) ([]model.Event, error)
+}
+
+// Notification - body of webhook request
+type Notification struct {
+	// Key - unique id of reminder, receiver may use it to drop repeated deliveries
+	Key           string      `json:"key"`
+	FireAt        time.Time   `json:"fire_at"`
+	MinutesBefore int         `json:"minutes_before"`
+	Event         model.Event `json:"event"`
+}
+
+// delivery - notification waiting to be sent
+type delivery struct {
+	Notification Notification `json:"notification"`
+	Attempts     int          `json:"attempts"`
+	NextAttempt  time.Time    `json:"next_attempt"`
+}
+
+// state - progress of scheduler saved on disk
+type state struct {
+	// Checkpoint - reminders fired up to it are already queued
+	Checkpoint time.Time  `json:"checkpoint"`
+	Pending    []delivery `json:"pending,omitempty"`
+}
+
+// Scheduler - fires reminders of events by POSTing them to webhook.
+// Failed deliveries are retried with exponential backoff, progress is kept in state file
+type Scheduler struct {
+	sync.Mutex
+	source     Source
+	webhookURL string
+	stateFile  string
+	state      state
+	// sending - keys of pending notifications which are being sent without lock
+	sending map[string]bool
+
+	client      *http.Client
+	now         func() time.Time
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	maxAttempts int
+
+	started   bool
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Option - optional behaviour of scheduler
+type Option func(s *Scheduler)
+
+// WithClock - replaces current time source, used by tests
+func WithClock(now func() time.Time) Option {
+	return func(s *Scheduler) {
+		s.now = now
+	}
+}
+
+// WithClient - sends webhook requests with client
+func WithClient(client *http.Client) Option {
+	return func(s *Scheduler) {
+		s.client = client
+	}
+}
+
+// WithRetries - sets delays between attempts and number of attempts before notification is dropped
+func WithRetries(minBackoff, maxBackoff time.Duration, maxAttempts int) Option {
+	return func(s *Scheduler) {
+		s.minBackoff, s.maxBackoff, s.maxAttempts = minBackoff, maxBackoff, maxAttempts
+	}
+}
+
+// New - creates scheduler and loads its state. Without state file reminders are fired from now on
+func New(source Source, webhookURL, stateFile string, opts ...Option) (*Scheduler, error) {
+	s := &Scheduler{
+		source:      source,
+		webhookURL:  webhookURL,
+		stateFile:   stateFile,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		now:         time.Now,
+		minBackoff:  time.Second,
+		maxBackoff:  5 * time.Minute,
+		maxAttempts: 10,
+		sending:     make(map[string]bool),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	err := os.MkdirAll(filepath.Dir(stateFile), 0750)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Clean(stateFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		s.state.Checkpoint = s.now()
+	case err != nil:
+		return nil, err
+	default:
+		err = json.Unmarshal(data, &s.state)
+		if err != nil {
+			return nil, fmt.Errorf("broken reminder state %s: %v", stateFile, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Start - runs Tick every interval until Close
+func (s *Scheduler) Start(interval time.Duration) {
+	s.started = true
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.Tick(); err != nil {
+					log.Printf("reminders: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close - stops scheduler started with Start and saves its state
+func (s *Scheduler) Close() error {
+	var err error
+
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		if s.started {
+			<-s.done
+		}
+
+		s.Lock()
+		err = s.save()
+		s.Unlock()
+	})
+
+	return err
+}
+
+// Tick - queues reminders fired since previous tick and sends notifications which are due.
+// Queue is saved before sending, so reminders are not lost if process stops.
+// Webhook is called without lock, so slow receiver does not block other calls
+func (s *Scheduler) Tick() error {
+	s.Lock()
+
+	now := s.now()
+
+	if now.After(s.state.Checkpoint) {
+		due, err := s.due(s.state.Checkpoint, now)
+		if err != nil {
+			s.Unlock()
+			return err
+		}
+
+		s.state.Pending = append(s.state.Pending, due...)
+		s.state.Checkpoint = now
+
+		err = s.save()
+		if err != nil {
+			s.Unlock()
+			return err
+		}
+	}
+
+	batch := s.take(now)
+	s.Unlock()
+
+	errs := make([]error, len(batch))
+	for i, d := range batch {
+		errs[i] = s.post(d.Notification)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.settle(batch, errs, now)
+
+	return s.save()
+}
+
+// Pending - returns number of notifications waiting to be sent
+func (s *Scheduler) Pending() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.state.Pending)
+}
+
+// due - returns reminders which fire in (from, to], must be called under lock
+func (s *Scheduler) due(from, to time.Time) ([]delivery, error) {
+	// reminder fires not earlier than MaxReminderLead before start
+	events, err := s.source.GetOccurrencesStarting(from, to.Add(model.MaxReminderLead))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []delivery
+	for _, event := range events {
+		for _, reminder := range event.Reminders {
+			fireAt := event.Date.Add(-reminder.Lead())
+			if !fireAt.After(from) || fireAt.After(to) {
+				continue
+			}
+
+			result = append(result, delivery{
+				Notification: Notification{
+					Key:           fmt.Sprintf("%d-%d-%d-%d", event.UserID, event.EventID, event.Date.Unix(), reminder.MinutesBefore),
+					FireAt:        fireAt,
+					MinutesBefore: reminder.MinutesBefore,
+					Event:         event,
+				},
+				NextAttempt: fireAt,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// take - returns notifications which are due and marks them as being sent.
+// They stay in queue until settle, so they are saved if process stops. Must be called under lock
+func (s *Scheduler) take(now time.Time) []delivery {
+	var batch []delivery
+
+	for _, d := range s.state.Pending {
+		if d.NextAttempt.After(now) || s.sending[d.Notification.Key] {
+			continue
+		}
+
+		s.sending[d.Notification.Key] = true
+		batch = append(batch, d)
+	}
+
+	return batch
+}
+
+// settle - applies results of sending batch: delivered notifications are removed from queue,
+// failed ones are rescheduled or dropped after the last attempt. Must be called under lock
+func (s *Scheduler) settle(batch []delivery, errs []error, now time.Time) {
+	failed := make(map[string]error)
+	for i, d := range batch {
+		delete(s.sending, d.Notification.Key)
+		failed[d.Notification.Key] = errs[i]
+	}
+
+	var pending []delivery
+
+	for _, d := range s.state.Pending {
+		err, sent := failed[d.Notification.Key]
+		if !sent {
+			pending = append(pending, d)
+			continue
+		}
+		if err == nil {
+			continue
+		}
+
+		d.Attempts++
+		if d.Attempts >= s.maxAttempts {
+			log.Printf("reminder %s is dropped after %d attempts: %v", d.Notification.Key, d.Attempts, err)
+			continue
+		}
+
+		d.NextAttempt = now.Add(s.backoff(d.Attempts))
+		pending = append(pending, d)
+	}
+
+	s.state.Pending = pending
+}
+
+// backoff - returns delay after failed attempt, it doubles with every attempt up to maxBackoff
+func (s *Scheduler) backoff(attempts int) time.Duration {
+	delay := s.minBackoff
+	for i := 1; i < attempts && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.maxBackoff {
+		delay = s.maxBackoff
+	}
+	return delay
+}
+
+// post - sends notification to webhook, any status except 2xx is failure
+func (s *Scheduler) post(n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", n.Key)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// save - atomically replaces state file, must be called under lock
+func (s *Scheduler) save() error {
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.stateFile + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, s.stateFile)
+}
@@ -0,0 +1,412 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"main.go/internal/model"
+	"main.go/internal/service"
+	"time"
+)
+
+// EventParams - params of createEvent and updateEvent
+type EventParams struct {
+	Event model.Event `json:"event"`
+}
+
+// EventRef - params of methods about one event, user may be omitted for acting user
+type EventRef struct {
+	UserID  int `json:"user_id"`
+	EventID int `json:"event_id"`
+	// Version - expected version of deleted event
+	Version int `json:"version,omitempty"`
+}
+
+// UserParams - params of methods about all events of user
+type UserParams struct {
+	UserID int `json:"user_id"`
+}
+
+// DateParams - params of eventsForDay, eventsForWeek and eventsForMonth, window is taken in time zone tz
+type DateParams struct {
+	UserID int    `json:"user_id"`
+	Date   string `json:"date"`
+	TZ     string `json:"tz,omitempty"`
+}
+
+// ConflictsParams - params of conflicts, event with event_id is skipped
+type ConflictsParams struct {
+	UserID  int    `json:"user_id"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	TZ      string `json:"tz,omitempty"`
+	EventID int    `json:"event_id,omitempty"`
+}
+
+// RangeParams - params of eventsInRange
+type RangeParams struct {
+	UserID int    `json:"user_id"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	TZ     string `json:"tz,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// Page - result of eventsInRange
+type Page struct {
+	Events []model.Event `json:"events"`
+	// NextCursor - cursor of the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// AnswerParams - params of respondEvent
+type AnswerParams struct {
+	// UserID - attendee
+	UserID int `json:"user_id"`
+	// OwnerID - owner of event
+	OwnerID int    `json:"owner_id"`
+	EventID int    `json:"event_id"`
+	Status  string `json:"status"`
+}
+
+// SearchParams - params of search, from and to are both given or both omitted
+type SearchParams struct {
+	UserID int    `json:"user_id"`
+	Q      string `json:"q"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	TZ     string `json:"tz,omitempty"`
+}
+
+// BatchParams - params of batch
+type BatchParams struct {
+	Atomic     bool              `json:"atomic"`
+	Operations []model.Operation `json:"operations"`
+}
+
+// BatchResult - result of single operation of batch
+type BatchResult struct {
+	Event *model.Event `json:"event,omitempty"`
+	Error *Error       `json:"error,omitempty"`
+}
+
+// FreeBusyParams - params of freeBusy and suggestSlot, duration is like 30m or 1h30m
+type FreeBusyParams struct {
+	UserIDs  []int  `json:"user_ids"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	TZ       string `json:"tz,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// register - returns methods of server by name
+func (s *Server) register() map[string]method {
+	return map[string]method{
+		"createEvent":    s.createEvent,
+		"updateEvent":    s.updateEvent,
+		"deleteEvent":    s.deleteEvent,
+		"getEvent":       s.getEvent,
+		"eventsForDay":   s.eventsFor(s.eventService.GetEventsForDay),
+		"eventsForWeek":  s.eventsFor(s.eventService.GetEventsForWeek),
+		"eventsForMonth": s.eventsFor(s.eventService.GetEventsForMonth),
+		"conflicts":      s.conflicts,
+		"eventsInRange":  s.eventsInRange,
+		"respondEvent":   s.respondEvent,
+		"invitations":    s.userEvents(s.eventService.GetInvitations),
+		"search":         s.search,
+		"batch":          s.batch,
+		"history":        s.history,
+		"restoreEvent":   s.restoreEvent,
+		"trash":          s.userEvents(s.eventService.GetTrash),
+		"freeBusy":       s.freeBusy,
+		"suggestSlot":    s.suggestSlot,
+	}
+}
+
+// createEvent - creates event and returns it with id and version
+func (s *Server) createEvent(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p EventParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	if err := s.eventService.CreateEvent(ctx, &p.Event); err != nil {
+		return nil, err
+	}
+	return p.Event, nil
+}
+
+// updateEvent - replaces event of version from event, returns it with the new version
+func (s *Server) updateEvent(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p EventParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	if err := s.eventService.UpdateEvent(ctx, &p.Event); err != nil {
+		return nil, err
+	}
+	return p.Event, nil
+}
+
+// deleteEvent - moves event of version to trash and returns it
+func (s *Server) deleteEvent(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p EventRef
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	return s.eventService.DeleteEvent(ctx, p.UserID, p.EventID, p.Version)
+}
+
+// getEvent - returns event, recurring event is not expanded
+func (s *Server) getEvent(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p EventRef
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	return s.eventService.GetEvent(ctx, p.UserID, p.EventID)
+}
+
+// eventsFor - returns method giving events of user in window of date
+func (s *Server) eventsFor(get func(ctx context.Context, userID int, date time.Time) ([]model.Event, error)) method {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p DateParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+
+		date, err := parseDate("date", p.Date, p.TZ)
+		if err != nil {
+			return nil, err
+		}
+
+		events, err := get(ctx, p.UserID, date)
+		return nonNil(events), err
+	}
+}
+
+// userEvents - returns method giving events of user
+func (s *Server) userEvents(get func(ctx context.Context, userID int) ([]model.Event, error)) method {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p UserParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+
+		events, err := get(ctx, p.UserID)
+		return nonNil(events), err
+	}
+}
+
+// conflicts - returns events of user which overlap [start, end)
+func (s *Server) conflicts(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p ConflictsParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	start, err := parseDate("start", p.Start, p.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := parseDate("end", p.End, p.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.eventService.GetConflicts(ctx, p.UserID, start, end, p.EventID)
+	return nonNil(events), err
+}
+
+// eventsInRange - returns page of user events which overlap [from, to) in chronological order
+func (s *Server) eventsInRange(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p RangeParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	from, err := parseDate("from", p.From, p.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parseDate("to", p.To, p.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.eventService.GetEventsInRange(ctx, p.UserID, from, to, p.Cursor, p.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return Page{Events: nonNil(page.Events), NextCursor: page.NextCursor}, nil
+}
+
+// respondEvent - saves answer of attendee to invitation
+func (s *Server) respondEvent(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p AnswerParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	return s.eventService.RespondEvent(ctx, p.UserID, p.OwnerID, p.EventID, p.Status)
+}
+
+// search - returns events of user with words starting with every word of q
+func (s *Server) search(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p SearchParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	var from, to time.Time
+	if p.From != "" || p.To != "" {
+		var err error
+		if from, err = parseDate("from", p.From, p.TZ); err != nil {
+			return nil, err
+		}
+		if to, err = parseDate("to", p.To, p.TZ); err != nil {
+			return nil, err
+		}
+	}
+
+	events, err := s.eventService.SearchEvents(ctx, p.UserID, p.Q, from, to)
+	return nonNil(events), err
+}
+
+// batch - applies operations in one transaction, results go in order of operations.
+// Failed operation of atomic batch has its error and other operations get CodeNotApplied
+func (s *Server) batch(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p BatchParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	applied, err := s.eventService.Batch(ctx, p.Operations, p.Atomic)
+	if applied == nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(applied))
+	for i, result := range applied {
+		if result.Err != nil {
+			results[i].Error = errorOf(result.Err)
+			continue
+		}
+		event := result.Event
+		results[i].Event = &event
+	}
+
+	return results, nil
+}
+
+// history - returns changes of event from the oldest one
+func (s *Server) history(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p EventRef
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	records, err := s.eventService.GetHistory(ctx, p.UserID, p.EventID)
+	if records == nil {
+		records = []model.AuditRecord{}
+	}
+	return records, err
+}
+
+// restoreEvent - moves deleted event back from trash and returns it with new version
+func (s *Server) restoreEvent(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p EventRef
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	return s.eventService.RestoreEvent(ctx, p.UserID, p.EventID)
+}
+
+// freeBusy - returns busy intervals of users in [from, to)
+func (s *Server) freeBusy(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p FreeBusyParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	from, to, err := period(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.eventService.GetFreeBusy(ctx, p.UserIDs, from, to)
+}
+
+// suggestSlot - returns the earliest common free slot of duration in working hours of users
+func (s *Server) suggestSlot(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p FreeBusyParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	from, to, err := period(p)
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := time.ParseDuration(p.Duration)
+	if err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: "duration should be positive, like 30m or 1h30m"}
+	}
+
+	return s.eventService.SuggestSlot(ctx, p.UserIDs, from, to, duration)
+}
+
+// period - returns from and to of free/busy params
+func period(p FreeBusyParams) (time.Time, time.Time, error) {
+	from, err := parseDate("from", p.From, p.TZ)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	to, err := parseDate("to", p.To, p.TZ)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return from, to, nil
+}
+
+// decode - decodes params of method, missing params are zero values and unknown fields are wrong
+func decode(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("wrong params: %v", err)}
+	}
+	return nil
+}
+
+// parseDate - parses date param in time zone tz
+func parseDate(name, value, tz string) (time.Time, error) {
+	date, err := service.ParseDate(value, tz)
+	if err != nil {
+		return time.Time{}, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("%s: %v", name, err)}
+	}
+	return date, nil
+}
+
+// nonNil - returns empty list in place of nil, so result is never null
+func nonNil(events []model.Event) []model.Event {
+	if events == nil {
+		return []model.Event{}
+	}
+	return events
+}
@@ -0,0 +1,293 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"main.go/internal/model"
+	"main.go/internal/service"
+	"net/http"
+)
+
+// version - version of protocol in every request and response
+const version = "2.0"
+
+const (
+	// defaultMaxBodyBytes - limit of request body when server is created without WithMaxBodyBytes
+	defaultMaxBodyBytes = 1 << 20
+	// maxBatchCalls - the biggest number of calls in one batch
+	maxBatchCalls = 100
+)
+
+// Codes of errors defined by JSON-RPC 2.0
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Codes of errors of service, they match statuses of HTTP front-end
+const (
+	// CodeUnavailable - business rule forbids operation or data store fails, 503
+	CodeUnavailable = -32000
+	// CodeForbidden - events of another user, 403
+	CodeForbidden = -32001
+	// CodeNotFound - event is missing, 404
+	CodeNotFound = -32002
+	// CodeConflict - event was changed after the expected version, 409
+	CodeConflict = -32003
+	// CodeVersionRequired - change does not name version it is based on, 428
+	CodeVersionRequired = -32004
+	// CodeQuota - user has no quota for one more event, 507
+	CodeQuota = -32005
+	// CodeNotApplied - operation of atomic batch is not applied because another one failed, 424
+	CodeNotApplied = -32006
+)
+
+// Request - call of method, request without id is notification and gets no response
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// Response - result or error of call with id of request
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Error - error of call
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error - returns text of error
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// method - procedure which decodes params and calls service
+type method func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// Server - JSON-RPC 2.0 front-end of service over HTTP, every call is POST with request or batch of requests.
+// Acting user is taken from request context like in HTTP front-end
+type Server struct {
+	eventService *service.Service
+	methods      map[string]method
+	// maxBodyBytes - limit of request body, bigger bodies get 413
+	maxBodyBytes int64
+}
+
+// Option - optional behaviour of server
+type Option func(s *Server)
+
+// WithMaxBodyBytes - sets limit of request body, like the same option of HTTP front-end
+func WithMaxBodyBytes(n int64) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxBodyBytes = n
+		}
+	}
+}
+
+// NewServer - creates JSON-RPC server calling given service
+func NewServer(svc *service.Service, opts ...Option) *Server {
+	s := &Server{eventService: svc, maxBodyBytes: defaultMaxBodyBytes}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.methods = s.register()
+	return s
+}
+
+// ServeHTTP - answers single request with response and batch with array of responses.
+// Notifications are answered with nothing, so request of notifications only gets 204
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		s.write(w, status, &Response{JSONRPC: version, Error: &Error{Code: CodeInvalidRequest, Message: err.Error()}, ID: null})
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		s.serveBatch(r.Context(), w, body)
+		return
+	}
+
+	response := s.call(r.Context(), body)
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.write(w, http.StatusOK, response)
+}
+
+// serveBatch - calls requests of batch one by one and answers with responses in the same order
+func (s *Server) serveBatch(ctx context.Context, w http.ResponseWriter, body []byte) {
+	var batch []json.RawMessage
+
+	err := json.Unmarshal(body, &batch)
+	if err != nil {
+		s.write(w, http.StatusOK, failed(null, &Error{Code: CodeParseError, Message: err.Error()}))
+		return
+	}
+
+	if len(batch) == 0 || len(batch) > maxBatchCalls {
+		message := fmt.Sprintf("batch should have from 1 to %d requests", maxBatchCalls)
+		s.write(w, http.StatusOK, failed(null, &Error{Code: CodeInvalidRequest, Message: message}))
+		return
+	}
+
+	responses := make([]*Response, 0, len(batch))
+	for _, raw := range batch {
+		if response := s.call(ctx, raw); response != nil {
+			responses = append(responses, response)
+		}
+	}
+
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.write(w, http.StatusOK, responses)
+}
+
+// null - id of response when id of request can not be read
+var null = json.RawMessage("null")
+
+// call - decodes request and calls its method, returns nil for notification
+func (s *Server) call(ctx context.Context, raw []byte) *Response {
+	var request Request
+
+	err := json.Unmarshal(raw, &request)
+	if err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return failed(null, &Error{Code: CodeParseError, Message: err.Error()})
+		}
+		return failed(null, &Error{Code: CodeInvalidRequest, Message: err.Error()})
+	}
+
+	id := request.ID
+	if id == nil {
+		id = null
+	}
+
+	if request.JSONRPC != version || request.Method == "" || !validID(request.ID) {
+		return failed(id, &Error{Code: CodeInvalidRequest, Message: `request should have jsonrpc "2.0", method and id of number or string`})
+	}
+
+	m, ok := s.methods[request.Method]
+	if !ok {
+		if request.ID == nil {
+			return nil
+		}
+		return failed(id, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method %q", request.Method)})
+	}
+
+	result, err := m(ctx, request.Params)
+
+	// notification is executed, but nobody waits for its result
+	if request.ID == nil {
+		return nil
+	}
+
+	if err != nil {
+		return failed(id, errorOf(err))
+	}
+
+	return &Response{JSONRPC: version, Result: result, ID: id}
+}
+
+// validID - checks that id is missing, null, number or string
+func validID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+
+	var value interface{}
+	if json.Unmarshal(id, &value) != nil {
+		return false
+	}
+
+	switch value.(type) {
+	case nil, float64, string:
+		return true
+	default:
+		return false
+	}
+}
+
+// failed - returns response with error
+func failed(id json.RawMessage, err *Error) *Response {
+	return &Response{JSONRPC: version, Error: err, ID: id}
+}
+
+// errorOf - converts error of service to error of call, problems of fields and current version go to data
+func errorOf(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	e := &Error{Message: err.Error()}
+
+	switch service.KindOf(err) {
+	case service.KindNotApplied:
+		e.Code = CodeNotApplied
+	case service.KindInvalid:
+		e.Code = CodeInvalidParams
+		var verr *model.ValidationError
+		if errors.As(err, &verr) {
+			e.Data = map[string]interface{}{"fields": verr.Fields}
+		}
+	case service.KindForbidden:
+		e.Code = CodeForbidden
+	case service.KindNotFound:
+		e.Code = CodeNotFound
+	case service.KindConflict:
+		e.Code = CodeConflict
+		if current, ok := service.CurrentVersion(err); ok {
+			e.Data = map[string]interface{}{"version": current}
+		}
+	case service.KindVersionRequired:
+		e.Code = CodeVersionRequired
+	case service.KindQuota:
+		e.Code = CodeQuota
+	default:
+		e.Code = CodeUnavailable
+	}
+
+	return e
+}
+
+// write - writes json document with status
+func (s *Server) write(w http.ResponseWriter, status int, document interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		body, _ = json.Marshal(failed(null, &Error{Code: CodeInternalError, Message: err.Error()}))
+	}
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"main.go/internal/model"
+	"time"
+)
+
+// userStore - data store which refuses operations on events of other users
+type userStore struct {
+	Store
+	userID int
+}
+
+// allow - checks that operation touches events of acting user
+func (s *userStore) allow(userID int) error {
+	if userID != s.userID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// CreateEvent - creates event of acting user
+func (s *userStore) CreateEvent(event *model.Event) error {
+	if err := s.allow(event.UserID); err != nil {
+		return err
+	}
+	return s.Store.CreateEvent(event)
+}
+
+// UpdateEvent - updates event of acting user
+func (s *userStore) UpdateEvent(userID, eventID int, newEvent *model.Event) error {
+	if err := s.allow(userID); err != nil {
+		return err
+	}
+	return s.Store.UpdateEvent(userID, eventID, newEvent)
+}
+
+// DeleteEvent - deletes event of acting user
+func (s *userStore) DeleteEvent(userID, eventID, version int) (model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return model.Event{}, err
+	}
+	return s.Store.DeleteEvent(userID, eventID, version)
+}
+
+// GetEventsForWeek - returns week of acting user
+func (s *userStore) GetEventsForWeek(date time.Time, userID int) ([]model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetEventsForWeek(date, userID)
+}
+
+// GetEventsForDay - returns day of acting user
+func (s *userStore) GetEventsForDay(date time.Time, userID int) ([]model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetEventsForDay(date, userID)
+}
+
+// GetEventsForMonth - returns month of acting user
+func (s *userStore) GetEventsForMonth(date time.Time, userID int) ([]model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetEventsForMonth(date, userID)
+}
+
+// GetConflicts - returns conflicts of acting user
+func (s *userStore) GetConflicts(userID int, from, to time.Time, excludeID int) ([]model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetConflicts(userID, from, to, excludeID)
+}
+
+// GetEvent - returns event of acting user
+func (s *userStore) GetEvent(userID, eventID int) (model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return model.Event{}, err
+	}
+	return s.Store.GetEvent(userID, eventID)
+}
+
+// GetEvents - returns events of acting user
+func (s *userStore) GetEvents(userID int) ([]model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetEvents(userID)
+}
+
+// GetEventsInRange - returns events of acting user
+func (s *userStore) GetEventsInRange(userID int, from, to time.Time, after model.Cursor, limit int) ([]model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetEventsInRange(userID, from, to, after, limit)
+}
+
+// RespondEvent - saves answer of acting user
+func (s *userStore) RespondEvent(attendeeID, ownerID, eventID int, status string) (model.Event, error) {
+	if err := s.allow(attendeeID); err != nil {
+		return model.Event{}, err
+	}
+	return s.Store.RespondEvent(attendeeID, ownerID, eventID, status)
+}
+
+// GetInvitations - returns invitations of acting user
+func (s *userStore) GetInvitations(userID int) ([]model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetInvitations(userID)
+}
+
+// Transaction - applies operations if all of them change events of acting user
+func (s *userStore) Transaction(ops []model.Operation, atomic bool) ([]model.OperationResult, error) {
+	for _, op := range ops {
+		if err := s.allow(op.Event.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return s.Store.Transaction(ops, atomic)
+}
+
+// RestoreEvent - restores deleted event of acting user
+func (s *userStore) RestoreEvent(userID, eventID int) (model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return model.Event{}, err
+	}
+	return s.Store.RestoreEvent(userID, eventID)
+}
+
+// GetTrash - returns deleted events of acting user
+func (s *userStore) GetTrash(userID int) ([]model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetTrash(userID)
+}
+
+// GetHistory - returns history of event of acting user
+func (s *userStore) GetHistory(userID, eventID int) ([]model.AuditRecord, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetHistory(userID, eventID)
+}
+
+// SearchEvents - searches events of acting user
+func (s *userStore) SearchEvents(userID int, terms []string, from, to time.Time) ([]model.Event, error) {
+	if err := s.allow(userID); err != nil {
+		return nil, err
+	}
+	return s.Store.SearchEvents(userID, terms, from, to)
+}
+
+// GetBusy - returns busy time of any user, intervals tell nothing about events
+func (s *userStore) GetBusy(userID int, from, to time.Time) ([]model.Interval, error) {
+	return s.Store.GetBusy(userID, from, to)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"main.go/internal/model"
+)
+
+// RespondEvent - saves answer of attendee to invitation of owner: accepted, declined, tentative or needs-action.
+// Attendee may be omitted for acting user
+func (s *Service) RespondEvent(ctx context.Context, attendeeID, ownerID, eventID int, status string) (model.Event, error) {
+	attendeeID, err := Acting(ctx, attendeeID)
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	var verr model.ValidationError
+
+	if attendeeID < 1 {
+		verr.Add("user_id", "userID should be positive")
+	}
+	if ownerID < 1 {
+		verr.Add("owner_id", "ownerID should be positive")
+	}
+	if eventID < 1 {
+		verr.Add("event_id", "eventID should be positive")
+	}
+	if !model.ValidStatus(status) {
+		verr.Add("status", fmt.Sprintf("status should be %s, %s, %s or %s",
+			model.Accepted, model.Declined, model.Tentative, model.NeedsAction))
+	}
+
+	if err = verr.Err(); err != nil {
+		return model.Event{}, err
+	}
+
+	return s.storeFor(ctx).RespondEvent(attendeeID, ownerID, eventID, status)
+}
+
+// GetInvitations - returns events user is invited to with answers of all attendees
+func (s *Service) GetInvitations(ctx context.Context, userID int) ([]model.Event, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeFor(ctx).GetInvitations(userID)
+}
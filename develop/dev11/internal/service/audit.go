@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"main.go/internal/model"
+)
+
+// GetHistory - returns who, when and how changed event, history of deleted event is kept
+func (s *Service) GetHistory(ctx context.Context, userID, eventID int) ([]model.AuditRecord, error) {
+	userID, err := s.event(ctx, userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeFor(ctx).GetHistory(userID, eventID)
+}
+
+// RestoreEvent - moves deleted event back from trash and returns it with new version
+func (s *Service) RestoreEvent(ctx context.Context, userID, eventID int) (model.Event, error) {
+	userID, err := s.event(ctx, userID, eventID)
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	return s.storeFor(ctx).RestoreEvent(userID, eventID)
+}
+
+// GetTrash - returns deleted events of user which can be restored
+func (s *Service) GetTrash(ctx context.Context, userID int) ([]model.Event, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeFor(ctx).GetTrash(userID)
+}
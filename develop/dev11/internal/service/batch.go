@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main.go/internal/model"
+)
+
+// MaxBatchOperations - the biggest number of operations in one batch
+const MaxBatchOperations = 1000
+
+// UnknownOperationError - operation of batch has unknown kind
+type UnknownOperationError struct {
+	Op string
+}
+
+// Error - returns text of error
+func (e *UnknownOperationError) Error() string {
+	return fmt.Sprintf("unknown operation %q, should be %s, %s or %s", e.Op, model.OpCreate, model.OpUpdate, model.OpDelete)
+}
+
+// Batch - applies create, update and delete operations in one transaction of data store, results go
+// in order of operations. Every operation is checked like single call of the same kind.
+// Atomic batch is applied entirely or not at all: failed operation is returned as *BatchError
+// with its index and other operations get ErrNotApplied
+func (s *Service) Batch(ctx context.Context, ops []model.Operation, atomic bool) ([]model.OperationResult, error) {
+	if len(ops) == 0 || len(ops) > MaxBatchOperations {
+		return nil, invalidf("batch should have from 1 to %d operations", MaxBatchOperations)
+	}
+
+	results := make([]model.OperationResult, len(ops))
+
+	// operations which pass checks go to data store, index maps them back to batch
+	var (
+		checked []model.Operation
+		index   []int
+	)
+
+	for i := range ops {
+		op := ops[i]
+
+		err := checkOperation(ctx, &op)
+		if err == nil {
+			checked = append(checked, op)
+			index = append(index, i)
+			continue
+		}
+
+		results[i] = model.OperationResult{Err: err}
+		if atomic {
+			return notApplied(results, i), &BatchError{Index: i, Err: err}
+		}
+	}
+
+	if len(checked) == 0 {
+		return results, nil
+	}
+
+	applied, err := s.storeFor(ctx).Transaction(checked, atomic)
+
+	var txErr *model.TransactionError
+	switch {
+	case errors.As(err, &txErr):
+		i := index[txErr.Index]
+		results[i] = model.OperationResult{Err: txErr.Err}
+		return notApplied(results, i), &BatchError{Index: i, Err: txErr.Err}
+	case err != nil:
+		return nil, err
+	}
+
+	for j, result := range applied {
+		results[index[j]] = result
+	}
+
+	return results, nil
+}
+
+// checkOperation - checks operation like single call of the same kind would be checked.
+// Acting user is applied to event
+func checkOperation(ctx context.Context, op *model.Operation) error {
+	event := &op.Event
+
+	var err error
+
+	event.UserID, err = Acting(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case model.OpCreate:
+		return validateEvent(event, false)
+	case model.OpUpdate:
+		err = validateEvent(event, true)
+		if err == nil && event.Version < 1 {
+			err = ErrVersionRequired
+		}
+		return err
+	case model.OpDelete:
+		var verr model.ValidationError
+		if event.UserID < 1 {
+			verr.Add("user_id", "userID should be positive")
+		}
+		if event.EventID < 1 {
+			verr.Add("event_id", "eventID should be positive")
+		}
+		err = verr.Err()
+		if err == nil && event.Version < 1 {
+			err = ErrVersionRequired
+		}
+		return err
+	default:
+		return invalid(&UnknownOperationError{Op: op.Op})
+	}
+}
+
+// notApplied - marks every result except failed one as not applied, used when atomic batch fails
+func notApplied(results []model.OperationResult, failed int) []model.OperationResult {
+	for i := range results {
+		if i != failed {
+			results[i] = model.OperationResult{Err: ErrNotApplied}
+		}
+	}
+	return results
+}
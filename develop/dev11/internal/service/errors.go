@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"main.go/internal/model"
+)
+
+// Kind - class of error, every transport turns it into its own status or code
+type Kind int
+
+const (
+	// KindUnavailable - business rule forbids operation or data store fails
+	KindUnavailable Kind = iota
+	// KindInvalid - wrong input of request
+	KindInvalid
+	// KindForbidden - events of another user
+	KindForbidden
+	// KindNotFound - event is missing
+	KindNotFound
+	// KindConflict - event was changed after the expected version
+	KindConflict
+	// KindVersionRequired - change does not name version it is based on
+	KindVersionRequired
+	// KindQuota - user has no quota for one more event
+	KindQuota
+	// KindNotApplied - operation of atomic batch is not applied because another operation failed
+	KindNotApplied
+)
+
+// ErrNotApplied - result of operation of atomic batch which is rolled back because another operation failed
+var ErrNotApplied = model.ErrNotApplied
+
+// BatchError - failed operation which rolled back atomic batch
+type BatchError struct {
+	// Index - position of operation in batch
+	Index int
+	Err   error
+}
+
+// Error - returns text of error
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d failed: %v", e.Index, e.Err)
+}
+
+// Unwrap - returns error of operation
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// InputError - request breaks rules of input, it is not about stored events
+type InputError struct {
+	Err error
+}
+
+// Error - returns text of error
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap - returns wrapped error
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// invalid - marks error as wrong input
+func invalid(err error) error {
+	return &InputError{Err: err}
+}
+
+// KindOf - returns class of error returned by service
+func KindOf(err error) Kind {
+	var (
+		input    *InputError
+		verr     *model.ValidationError
+		notFound *model.NotFoundError
+		conflict *model.VersionConflictError
+		quota    *model.QuotaError
+	)
+
+	switch {
+	case errors.Is(err, ErrNotApplied):
+		return KindNotApplied
+	case errors.As(err, &input), errors.As(err, &verr):
+		return KindInvalid
+	case errors.Is(err, ErrForbidden):
+		return KindForbidden
+	case errors.Is(err, ErrVersionRequired):
+		return KindVersionRequired
+	case errors.As(err, &notFound):
+		return KindNotFound
+	case errors.As(err, &conflict):
+		return KindConflict
+	case errors.As(err, &quota):
+		return KindQuota
+	default:
+		return KindUnavailable
+	}
+}
+
+// CurrentVersion - returns current version of event from error of stale version
+func CurrentVersion(err error) (int, bool) {
+	var conflict *model.VersionConflictError
+	if errors.As(err, &conflict) {
+		return conflict.Version, true
+	}
+	return 0, false
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main.go/internal/model"
+	"time"
+)
+
+// CreateEvent - checks event and creates it, event without id gets id from data store.
+// Event may omit acting user
+func (s *Service) CreateEvent(ctx context.Context, event *model.Event) error {
+	var err error
+
+	event.UserID, err = Acting(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = validateEvent(event, false)
+	if err != nil {
+		return err
+	}
+
+	return s.storeFor(ctx).CreateEvent(event)
+}
+
+// UpdateEvent - checks event and replaces stored one if its version is event.Version,
+// AnyVersion replaces any one. Event gets the new version
+func (s *Service) UpdateEvent(ctx context.Context, event *model.Event) error {
+	var err error
+
+	event.UserID, err = Acting(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = validateEvent(event, true)
+	if err != nil {
+		return err
+	}
+
+	event.Version, err = storeVersion(event.Version)
+	if err != nil {
+		return err
+	}
+
+	return s.storeFor(ctx).UpdateEvent(event.UserID, event.EventID, event)
+}
+
+// DeleteEvent - moves event of version to trash and returns it, AnyVersion deletes any one.
+// Missing event is reported before missing version
+func (s *Service) DeleteEvent(ctx context.Context, userID, eventID, version int) (model.Event, error) {
+	userID, err := s.event(ctx, userID, eventID)
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	expected, err := storeVersion(version)
+	if err != nil {
+		if _, getErr := s.storeFor(ctx).GetEvent(userID, eventID); getErr != nil {
+			return model.Event{}, getErr
+		}
+		return model.Event{}, err
+	}
+
+	return s.storeFor(ctx).DeleteEvent(userID, eventID, expected)
+}
+
+// GetEvent - returns event, recurring event is not expanded
+func (s *Service) GetEvent(ctx context.Context, userID, eventID int) (model.Event, error) {
+	userID, err := s.event(ctx, userID, eventID)
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	return s.storeFor(ctx).GetEvent(userID, eventID)
+}
+
+// GetEvents - returns all events of user
+func (s *Service) GetEvents(ctx context.Context, userID int) ([]model.Event, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeFor(ctx).GetEvents(userID)
+}
+
+// GetEventsForDay - returns occurrences of user events in day of date, window is taken in time zone of date
+func (s *Service) GetEventsForDay(ctx context.Context, userID int, date time.Time) ([]model.Event, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeFor(ctx).GetEventsForDay(date, userID)
+}
+
+// GetEventsForWeek - returns occurrences of user events in week of date, window is taken in time zone of date
+func (s *Service) GetEventsForWeek(ctx context.Context, userID int, date time.Time) ([]model.Event, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeFor(ctx).GetEventsForWeek(date, userID)
+}
+
+// GetEventsForMonth - returns occurrences of user events in month of date, window is taken in time zone of date
+func (s *Service) GetEventsForMonth(ctx context.Context, userID int, date time.Time) ([]model.Event, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeFor(ctx).GetEventsForMonth(date, userID)
+}
+
+// GetConflicts - returns events of user which overlap [start, end), event excludeID is skipped
+func (s *Service) GetConflicts(ctx context.Context, userID int, start, end time.Time, excludeID int) ([]model.Event, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !end.After(start) {
+		return nil, invalidf("end should be after start")
+	}
+
+	return s.storeFor(ctx).GetConflicts(userID, start, end, excludeID)
+}
+
+// ImportEvents - creates events for user in one atomic transaction, they get new ids.
+// Nothing is created if any event is wrong or can not be stored
+func (s *Service) ImportEvents(ctx context.Context, userID int, events []model.Event) error {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	ops := make([]model.Operation, len(events))
+	for i := range events {
+		events[i].UserID = userID
+		events[i].EventID = 0
+
+		err = events[i].Validate()
+		if err != nil {
+			return invalid(fmt.Errorf("event %d %q: %w", i+1, events[i].Title, err))
+		}
+
+		ops[i] = model.Operation{Op: model.OpCreate, Event: events[i]}
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	results, err := s.storeFor(ctx).Transaction(ops, true)
+
+	var txErr *model.TransactionError
+	switch {
+	case errors.As(err, &txErr):
+		return fmt.Errorf("event %d %q is not imported, nothing is created: %w",
+			txErr.Index+1, events[txErr.Index].Title, txErr.Err)
+	case err != nil:
+		return err
+	}
+
+	for i, result := range results {
+		events[i] = result.Event
+	}
+
+	return nil
+}
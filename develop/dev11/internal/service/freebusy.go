@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"main.go/internal/model"
+	"time"
+)
+
+const (
+	// MaxFreeBusyUsers - number of users in one request of free/busy or slot
+	MaxFreeBusyUsers = 100
+	// maxFreeBusyRange - longest period of one request of free/busy or slot
+	maxFreeBusyRange = 92 * 24 * time.Hour
+)
+
+// ErrNoSlot - users have no common free time of requested duration
+var ErrNoSlot = errors.New("no common free slot of requested duration in working hours")
+
+// FreeBusy - busy time of one user
+type FreeBusy struct {
+	UserID int              `json:"user_id"`
+	Busy   []model.Interval `json:"busy"`
+}
+
+// WithWorkingHours - sets working hours of users for slot suggestions, users not listed have default ones
+func WithWorkingHours(defaults model.WorkingHours, users map[int]model.WorkingHours) Option {
+	return func(s *Service) {
+		s.defaultHours = defaults
+		s.workingHours = users
+	}
+}
+
+// GetFreeBusy - returns busy intervals in [from, to) of every user in order of userIDs without repeats,
+// events themselves are not shown. Busy time of any user is visible, intervals are given in time zone of from
+func (s *Service) GetFreeBusy(ctx context.Context, userIDs []int, from, to time.Time) ([]FreeBusy, error) {
+	userIDs, err := checkFreeBusy(userIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	busy, err := s.busy(ctx, userIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]FreeBusy, len(userIDs))
+	for i, uID := range userIDs {
+		result[i] = FreeBusy{UserID: uID, Busy: busy[i]}
+	}
+
+	return result, nil
+}
+
+// SuggestSlot - returns the earliest slot of duration in [from, to) when all users are free
+// and inside their working hours, in time zone of from
+func (s *Service) SuggestSlot(ctx context.Context, userIDs []int, from, to time.Time, duration time.Duration) (model.Interval, error) {
+	userIDs, err := checkFreeBusy(userIDs, from, to)
+	if err != nil {
+		return model.Interval{}, err
+	}
+
+	if duration <= 0 {
+		return model.Interval{}, invalidf("duration should be positive")
+	}
+
+	busy, err := s.busy(ctx, userIDs, from, to)
+	if err != nil {
+		return model.Interval{}, err
+	}
+
+	hours := make([]model.WorkingHours, len(userIDs))
+	for i, uID := range userIDs {
+		hours[i] = s.hoursOf(uID)
+	}
+
+	slot, ok := model.FindSlot(from, to, duration, busy, hours)
+	if !ok {
+		return model.Interval{}, ErrNoSlot
+	}
+
+	loc := from.Location()
+	return model.Interval{Start: slot.Start.In(loc), End: slot.End.In(loc)}, nil
+}
+
+// checkFreeBusy - checks users and period of request, returns users without repeats
+func checkFreeBusy(userIDs []int, from, to time.Time) ([]int, error) {
+	unique := make([]int, 0, len(userIDs))
+	seen := make(map[int]bool)
+	for _, uID := range userIDs {
+		if uID < 1 {
+			return nil, invalidf("user_ids should be positive")
+		}
+		if !seen[uID] {
+			seen[uID] = true
+			unique = append(unique, uID)
+		}
+	}
+
+	if len(unique) == 0 || len(unique) > MaxFreeBusyUsers {
+		return nil, invalidf("user_ids should have from 1 to %d users", MaxFreeBusyUsers)
+	}
+
+	if !to.After(from) {
+		return nil, invalidf("to should be after from")
+	}
+	if to.Sub(from) > maxFreeBusyRange {
+		return nil, invalidf("period should not be longer than %d days", maxFreeBusyRange/(24*time.Hour))
+	}
+
+	return unique, nil
+}
+
+// busy - returns busy intervals of users in time zone of from, never nil
+func (s *Service) busy(ctx context.Context, userIDs []int, from, to time.Time) ([][]model.Interval, error) {
+	loc := from.Location()
+	result := make([][]model.Interval, len(userIDs))
+
+	for i, uID := range userIDs {
+		intervals, err := s.storeFor(ctx).GetBusy(uID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = make([]model.Interval, len(intervals))
+		for j, interval := range intervals {
+			result[i][j] = model.Interval{Start: interval.Start.In(loc), End: interval.End.In(loc)}
+		}
+	}
+
+	return result, nil
+}
+
+// hoursOf - returns working hours of user
+func (s *Service) hoursOf(userID int) model.WorkingHours {
+	if hours, ok := s.workingHours[userID]; ok {
+		return hours
+	}
+	return s.defaultHours
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"main.go/internal/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPageLimit - size of page when limit is not given
+	DefaultPageLimit = 100
+	// MaxPageLimit - the biggest page
+	MaxPageLimit = 1000
+)
+
+// ErrPageLimit - limit of page is out of range
+var ErrPageLimit = invalidf("limit should be from 1 to %d", MaxPageLimit)
+
+// Page - part of events in chronological order
+type Page struct {
+	Events []model.Event
+	// NextCursor - cursor of the next page, empty on the last page
+	NextCursor string
+}
+
+// GetEventsInRange - returns page of user events which overlap [from, to) after cursor.
+// Empty cursor is the beginning of list, zero limit is DefaultPageLimit
+func (s *Service) GetEventsInRange(ctx context.Context, userID int, from, to time.Time, cursor string, limit int) (Page, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return Page{}, err
+	}
+
+	if !to.After(from) {
+		return Page{}, invalidf("to should be after from")
+	}
+
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit < 1 || limit > MaxPageLimit {
+		return Page{}, ErrPageLimit
+	}
+
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, invalid(err)
+	}
+
+	// one more event tells if there is the next page
+	events, err := s.storeFor(ctx).GetEventsInRange(userID, from, to, after, limit+1)
+	if err != nil {
+		return Page{}, err
+	}
+
+	page := Page{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		last := page.Events[limit-1]
+		page.NextCursor = encodeCursor(model.Cursor{Date: last.Date.Time, EventID: last.EventID, UserID: last.UserID})
+	}
+
+	return page, nil
+}
+
+// encodeCursor - returns opaque string with position of occurrence
+func encodeCursor(c model.Cursor) string {
+	raw := fmt.Sprintf("%d:%d:%d", c.Date.UnixNano(), c.EventID, c.UserID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor - parses cursor from encodeCursor, empty string is the beginning of list
+func decodeCursor(s string) (model.Cursor, error) {
+	if s == "" {
+		return model.Cursor{}, nil
+	}
+
+	errCursor := errors.New("wrong cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return model.Cursor{}, errCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return model.Cursor{}, errCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return model.Cursor{}, errCursor
+	}
+
+	eventID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return model.Cursor{}, errCursor
+	}
+
+	userID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return model.Cursor{}, errCursor
+	}
+
+	return model.Cursor{Date: time.Unix(0, nanos), EventID: eventID, UserID: userID}, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"fmt"
+	"iter"
+	"main.go/internal/model"
+	"sort"
+	"time"
+)
+
+// overlapHorizon - how far two recurring events are compared for overlaps, endless series can not be expanded fully
+const overlapHorizon = 2 * 365 * 24 * time.Hour
+
+// Rules - rules of stored events checked by data store under its lock: quota of events and overlaps.
+// Zero rules allow everything
+type Rules struct {
+	// MaxEvents - quota of events of one user, events in trash and invitations are not counted.
+	// Trash of user keeps so many latest deleted events too, the earlier ones are purged with their history
+	MaxEvents int
+	// RejectOverlaps - forbids events which overlap other events of the same user
+	RejectOverlaps bool
+}
+
+// CheckPut - returns *model.QuotaError if user can not have one more event
+// and error if event overlaps another event of its owner
+func (r Rules) CheckPut(event model.Event, stored iter.Seq[model.Event], count int, added bool) error {
+	if added && r.MaxEvents > 0 && count >= r.MaxEvents {
+		return &model.QuotaError{UserID: event.UserID, Limit: r.MaxEvents}
+	}
+
+	if r.RejectOverlaps {
+		return checkOverlaps(event, stored)
+	}
+
+	return nil
+}
+
+// TrashLimit - returns quota of events, trash keeps as many deleted events
+func (r Rules) TrashLimit() int {
+	return r.MaxEvents
+}
+
+// interval - time taken by one occurrence of event
+type interval struct {
+	start   time.Time
+	end     time.Time
+	eventID int
+}
+
+// checkOverlaps - returns error if event overlaps another stored event
+func checkOverlaps(event model.Event, stored iter.Seq[model.Event]) error {
+	if event.Duration() == 0 {
+		return nil
+	}
+
+	for other := range stored {
+		if other.EventID == event.EventID || other.Duration() == 0 {
+			continue
+		}
+
+		if a, b, ok := overlap(event, other); ok {
+			return fmt.Errorf("event overlaps event %d: %s - %s and %s - %s", b.eventID,
+				a.start.Format(time.RFC3339), a.end.Format(time.RFC3339),
+				b.start.Format(time.RFC3339), b.end.Format(time.RFC3339))
+		}
+	}
+
+	return nil
+}
+
+// overlap - finds the first pair of overlapping occurrences of event and other one.
+// Single event is checked against occurrences of other one in its own time, however far it is.
+// Two recurring events are compared only for overlapHorizon from the later start
+func overlap(event, other model.Event) (interval, interval, bool) {
+	var from, to time.Time
+
+	switch {
+	case event.Recurrence == nil:
+		from, to = event.Date.Time, event.Date.Add(event.Duration())
+	case other.Recurrence == nil:
+		from, to = other.Date.Time, other.Date.Add(other.Duration())
+	default:
+		from = event.Date.Time
+		if other.Date.After(from) {
+			from = other.Date.Time
+		}
+		to = from.Add(overlapHorizon)
+	}
+
+	return firstOverlap(intervalsOf(event, from, to), intervalsOf(other, from, to))
+}
+
+// intervalsOf - returns sorted intervals of event occurrences which overlap [from, to)
+func intervalsOf(event model.Event, from, to time.Time) []interval {
+	duration := event.Duration()
+	occurrences := event.Occurrences(from, to)
+
+	intervals := make([]interval, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		intervals = append(intervals, interval{start: occurrence, end: occurrence.Add(duration), eventID: event.EventID})
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	return intervals
+}
+
+// firstOverlap - finds the first pair of overlapping intervals in two sorted lists
+func firstOverlap(a, b []interval) (interval, interval, bool) {
+	i, j := 0, 0
+
+	for i < len(a) && j < len(b) {
+		if model.Overlaps(a[i].start, a[i].end, b[j].start, b[j].end) {
+			return a[i], b[j], true
+		}
+
+		// interval which ends first can not overlap anything further in other list
+		if a[i].end.Before(b[j].end) {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return interval{}, interval{}, false
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"main.go/internal/model"
+	"time"
+)
+
+// SearchEvents - returns events of user with words starting with every word of q.
+// With non-zero from and to only occurrences overlapping [from, to) are returned
+func (s *Service) SearchEvents(ctx context.Context, userID int, q string, from, to time.Time) ([]model.Event, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := model.Terms(q)
+	if len(terms) == 0 {
+		return nil, invalidf("q should have at least one word")
+	}
+
+	if (!from.IsZero() || !to.IsZero()) && !to.After(from) {
+		return nil, invalidf("to should be after from")
+	}
+
+	return s.storeFor(ctx).SearchEvents(userID, terms, from, to)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main.go/internal/model"
+	"time"
+)
+
+// AnyVersion - expected version which matches every version of event, like If-Match: *
+const AnyVersion = -1
+
+var (
+	// ErrForbidden - acting user asks for events of another user
+	ErrForbidden = errors.New("access to events of another user is forbidden")
+	// ErrVersionRequired - change of event does not name version it is based on
+	ErrVersionRequired = errors.New("version of event is required")
+)
+
+// Store - interface for working with data store
+type Store interface {
+	CreateEvent(event *model.Event) error
+	UpdateEvent(userID, eventID int, newEvent *model.Event) error
+	DeleteEvent(userID, eventID, version int) (model.Event, error)
+	GetEventsForWeek(date time.Time, userID int) ([]model.Event, error)
+	GetEventsForDay(date time.Time, userID int) ([]model.Event, error)
+	GetEventsForMonth(date time.Time, userID int) ([]model.Event, error)
+	GetConflicts(userID int, from, to time.Time, excludeID int) ([]model.Event, error)
+	GetEvent(userID, eventID int) (model.Event, error)
+	GetEvents(userID int) ([]model.Event, error)
+	GetEventsInRange(userID int, from, to time.Time, after model.Cursor, limit int) ([]model.Event, error)
+	RespondEvent(attendeeID, ownerID, eventID int, status string) (model.Event, error)
+	GetInvitations(userID int) ([]model.Event, error)
+	SearchEvents(userID int, terms []string, from, to time.Time) ([]model.Event, error)
+	Transaction(ops []model.Operation, atomic bool) ([]model.OperationResult, error)
+	RestoreEvent(userID, eventID int) (model.Event, error)
+	GetTrash(userID int) ([]model.Event, error)
+	GetHistory(userID, eventID int) ([]model.AuditRecord, error)
+	GetBusy(userID int, from, to time.Time) ([]model.Interval, error)
+	Size() (users, events int)
+}
+
+// Service - business logic of calendar: rules of input and access to events.
+// Rules of stored events are given to data store as Rules, it checks them under its lock.
+// Service knows nothing about transport, every front-end calls the same methods
+type Service struct {
+	store Store
+	// defaultHours, workingHours - working hours of users for slot suggestions
+	defaultHours model.WorkingHours
+	workingHours map[int]model.WorkingHours
+}
+
+// Option - optional behaviour of service
+type Option func(s *Service)
+
+// New - creates service working with given data store
+func New(store Store, opts ...Option) *Service {
+	s := &Service{
+		store:        store,
+		defaultHours: model.DefaultWorkingHours(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// actorKey - key of acting user in context
+type actorKey struct{}
+
+// WithActor - returns context of request made by authenticated user, service lets it reach only own events
+func WithActor(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// Actor - returns authenticated user of context
+func Actor(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(actorKey{}).(int)
+	return userID, ok
+}
+
+// Acting - returns user request acts for: zero is replaced with acting user, another user is forbidden
+func Acting(ctx context.Context, userID int) (int, error) {
+	actor, ok := Actor(ctx)
+	if !ok {
+		return userID, nil
+	}
+	if userID != 0 && userID != actor {
+		return 0, ErrForbidden
+	}
+	return actor, nil
+}
+
+// User - returns user whose events are asked for, acting user may be omitted with zero
+func (s *Service) User(ctx context.Context, userID int) (int, error) {
+	userID, err := Acting(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if userID < 1 {
+		return 0, invalidf("userID should be positive")
+	}
+	return userID, nil
+}
+
+// event - returns user and event asked for
+func (s *Service) event(ctx context.Context, userID, eventID int) (int, error) {
+	userID, err := s.User(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if eventID < 1 {
+		return 0, invalidf("eventID should be positive")
+	}
+	return userID, nil
+}
+
+// storeFor - returns data store for context, authenticated request can reach only events of its user
+func (s *Service) storeFor(ctx context.Context) Store {
+	if actor, ok := Actor(ctx); ok {
+		return &userStore{Store: s.store, userID: actor}
+	}
+	return s.store
+}
+
+// storeVersion - converts expected version to the one of data store, where zero is any version
+func storeVersion(version int) (int, error) {
+	switch {
+	case version == AnyVersion:
+		return 0, nil
+	case version < 1:
+		return 0, ErrVersionRequired
+	default:
+		return version, nil
+	}
+}
+
+// ParseDate - parsing date from string and moving it to time zone tz.
+// Date without offset is read in tz, empty tz keeps offset of date or UTC
+func ParseDate(date, tz string) (time.Time, error) {
+	if tz == "" {
+		parsed, err := model.ParseDate(date, time.UTC)
+		if err != nil {
+			return time.Time{}, invalid(err)
+		}
+		return parsed, nil
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, invalidf("unknown time zone %q: %v", tz, err)
+	}
+
+	parsed, err := model.ParseDate(date, loc)
+	if err != nil {
+		return time.Time{}, invalid(err)
+	}
+
+	return parsed.In(loc), nil
+}
+
+// validateEvent - checks ids and business rules of event, event id may be omitted only if idRequired is false
+func validateEvent(event *model.Event, idRequired bool) error {
+	var verr model.ValidationError
+
+	if event.UserID < 1 {
+		verr.Add("user_id", "userID should be positive")
+	}
+	if event.EventID < 0 || (idRequired && event.EventID == 0) {
+		verr.Add("event_id", "eventID should be positive")
+	}
+
+	var rules *model.ValidationError
+	if errors.As(event.Validate(), &rules) {
+		for field, problem := range rules.Fields {
+			verr.Add(field, problem)
+		}
+	}
+
+	return verr.Err()
+}
+
+// invalidf - returns InputError with formatted text
+func invalidf(format string, args ...interface{}) error {
+	return &InputError{Err: fmt.Errorf(format, args...)}
+}
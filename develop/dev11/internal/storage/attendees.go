@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"fmt"
+	"main.go/internal/model"
+	"main.go/internal/stream"
+	"time"
+)
+
+// eventRef - key of event in the whole data store
+type eventRef struct {
+	owner userKey
+	id    eventKey
+}
+
+// RespondEvent - saves answer of attendee to invitation and returns updated event
+func (e *EventStorage) RespondEvent(attendeeID, ownerID, eventID int, status string) (model.Event, error) {
+	if !model.ValidStatus(status) {
+		return model.Event{}, fmt.Errorf("unknown status %q", status)
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	event, ok := e.lookup(ownerID, eventID)
+	if !ok {
+		return model.Event{}, &model.NotFoundError{UserID: ownerID, EventID: eventID}
+	}
+
+	// event user is not invited to stays hidden from that user
+	if _, invited := event.Attendee(attendeeID); !invited {
+		return model.Event{}, &model.NotFoundError{UserID: ownerID, EventID: eventID}
+	}
+
+	old := event
+
+	// stored event shares attendees with events returned before, so they are copied
+	attendees := make([]model.Attendee, len(event.Attendees))
+	for i, attendee := range event.Attendees {
+		if attendee.UserID == attendeeID {
+			attendee.Status = status
+		}
+		attendees[i] = attendee
+	}
+	event.Attendees = attendees
+	event.Version++
+
+	audit := newAudit(model.ActionRespond, attendeeID, &old, &event)
+	if err := e.journalPut(event, audit); err != nil {
+		return model.Event{}, err
+	}
+	e.restore(event)
+	e.remember(audit)
+	e.publish(stream.Update, event, event)
+
+	return event, nil
+}
+
+// GetInvitations - returns events user is invited to with answers, sorted by date
+func (e *EventStorage) GetInvitations(userID int) ([]model.Event, error) {
+	e.RLock()
+
+	var events []model.Event
+	for ref := range e.invited[userKey(userID)] {
+		if event, ok := e.lookup(int(ref.owner), int(ref.id)); ok {
+			events = append(events, event)
+		}
+	}
+
+	e.RUnlock()
+
+	sortEvents(events)
+
+	return events, nil
+}
+
+// invitedBetween - returns occurrences of events user is invited to and has not declined,
+// which overlap [from, to) and go after cursor, must be called under lock
+func (e *EventStorage) invitedBetween(userID int, from, to time.Time, after model.Cursor) []model.Event {
+	var result []model.Event
+
+	for ref := range e.invited[userKey(userID)] {
+		event, ok := e.lookup(int(ref.owner), int(ref.id))
+		if !ok {
+			continue
+		}
+
+		if attendee, _ := event.Attendee(userID); attendee.Status == model.Declined {
+			continue
+		}
+
+		for _, occurrence := range event.Occurrences(from, to) {
+			if after.Before(occurrence, event.EventID, event.UserID) {
+				result = append(result, occurrenceOf(event, occurrence))
+			}
+		}
+	}
+
+	return result
+}
+
+// keepAnswers - returns attendees of updated event, answers of users invited before are kept,
+// new attendees have not answered yet
+func keepAnswers(old model.Event, attendees []model.Attendee) []model.Attendee {
+	if len(attendees) == 0 {
+		return nil
+	}
+
+	result := make([]model.Attendee, len(attendees))
+	for i, attendee := range attendees {
+		attendee.Status = model.NeedsAction
+		if before, ok := old.Attendee(attendee.UserID); ok {
+			attendee.Status = before.Status
+		}
+		result[i] = attendee
+	}
+
+	return result
+}
+
+// indexAttendees - adds event to invitations of its attendees, must be called under lock
+func (e *EventStorage) indexAttendees(event model.Event) {
+	ref := eventRef{owner: userKey(event.UserID), id: eventKey(event.EventID)}
+
+	for _, attendee := range event.Attendees {
+		refs, ok := e.invited[userKey(attendee.UserID)]
+		if !ok {
+			refs = make(map[eventRef]struct{})
+			e.invited[userKey(attendee.UserID)] = refs
+		}
+		refs[ref] = struct{}{}
+	}
+}
+
+// unindexAttendees - removes event from invitations of its attendees, must be called under lock
+func (e *EventStorage) unindexAttendees(event model.Event) {
+	ref := eventRef{owner: userKey(event.UserID), id: eventKey(event.EventID)}
+
+	for _, attendee := range event.Attendees {
+		refs := e.invited[userKey(attendee.UserID)]
+		delete(refs, ref)
+		if len(refs) == 0 {
+			delete(e.invited, userKey(attendee.UserID))
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"main.go/internal/model"
+	"main.go/internal/stream"
+	"time"
+)
+
+// maxHistory - history of event keeps so many latest changes
+const maxHistory = 100
+
+// RestoreEvent - moves event of user from trash back to calendar and returns it
+func (e *EventStorage) RestoreEvent(userID, eventID int) (model.Event, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	deleted, ok := e.trash[userKey(userID)][eventKey(eventID)]
+	if !ok {
+		return model.Event{}, &model.NotFoundError{UserID: userID, EventID: eventID}
+	}
+
+	event := deleted
+	event.Version = deleted.Version + 1
+
+	if err := e.checkRules(event, true); err != nil {
+		return model.Event{}, err
+	}
+
+	audit := newAudit(model.ActionRestore, userID, &deleted, &event)
+	if err := e.journalPut(event, audit); err != nil {
+		return model.Event{}, err
+	}
+	e.restore(event)
+	e.remember(audit)
+	e.publish(stream.Create, event, event)
+
+	return event, nil
+}
+
+// GetTrash - returns deleted events of user which can be restored, sorted by date
+func (e *EventStorage) GetTrash(userID int) ([]model.Event, error) {
+	e.RLock()
+
+	events := make([]model.Event, 0, len(e.trash[userKey(userID)]))
+	for _, event := range e.trash[userKey(userID)] {
+		events = append(events, event)
+	}
+
+	e.RUnlock()
+
+	sortEvents(events)
+
+	return events, nil
+}
+
+// GetHistory - returns changes of event from the oldest one, history of deleted event is kept
+func (e *EventStorage) GetHistory(userID, eventID int) ([]model.AuditRecord, error) {
+	e.RLock()
+	defer e.RUnlock()
+
+	records := e.history[eventRef{owner: userKey(userID), id: eventKey(eventID)}]
+	if len(records) == 0 {
+		return nil, &model.NotFoundError{UserID: userID, EventID: eventID}
+	}
+
+	return append([]model.AuditRecord(nil), records...), nil
+}
+
+// newAudit - returns record of change made now by actor
+func newAudit(action string, actorID int, before, after *model.Event) model.AuditRecord {
+	record := model.AuditRecord{Action: action, ActorID: actorID, At: time.Now().UTC()}
+
+	// record keeps copies, so later changes of events do not rewrite history
+	if before != nil {
+		event := *before
+		record.Before = &event
+		record.UserID, record.EventID = event.UserID, event.EventID
+	}
+	if after != nil {
+		event := *after
+		record.After = &event
+		record.UserID, record.EventID = event.UserID, event.EventID
+	}
+
+	return record
+}
+
+// remember - appends record to history of its event, the oldest records are dropped.
+// Must be called under lock
+func (e *EventStorage) remember(record model.AuditRecord) {
+	ref := eventRef{owner: userKey(record.UserID), id: eventKey(record.EventID)}
+
+	records := append(e.history[ref], record)
+	if len(records) > maxHistory {
+		records = append([]model.AuditRecord(nil), records[len(records)-maxHistory:]...)
+	}
+	e.history[ref] = records
+}
+
+// forgetLast - removes the latest record of event history, used to undo change. Must be called under lock
+func (e *EventStorage) forgetLast(userID, eventID int) {
+	ref := eventRef{owner: userKey(userID), id: eventKey(eventID)}
+
+	records := e.history[ref]
+	if len(records) <= 1 {
+		delete(e.history, ref)
+		return
+	}
+	e.history[ref] = records[:len(records)-1]
+}
+
+// discard - removes event from calendar and puts it into trash, must be called under lock
+func (e *EventStorage) discard(event model.Event) {
+	e.forget(event.UserID, event.EventID)
+
+	user, id := userKey(event.UserID), eventKey(event.EventID)
+
+	events, ok := e.trash[user]
+	if !ok {
+		events = make(map[eventKey]model.Event)
+		e.trash[user] = events
+	}
+	if _, ok := events[id]; !ok {
+		e.trashOrder[user] = append(e.trashOrder[user], id)
+	}
+	events[id] = event
+}
+
+// untrash - removes event from trash if it is there, must be called under lock
+func (e *EventStorage) untrash(userID, eventID int) {
+	user, id := userKey(userID), eventKey(eventID)
+
+	events := e.trash[user]
+	if _, ok := events[id]; !ok {
+		return
+	}
+
+	delete(events, id)
+	if len(events) == 0 {
+		delete(e.trash, user)
+		delete(e.trashOrder, user)
+		return
+	}
+
+	order := e.trashOrder[user]
+	for i := range order {
+		if order[i] == id {
+			e.trashOrder[user] = append(order[:i:i], order[i+1:]...)
+			break
+		}
+	}
+}
+
+// purgeTrash - drops the earliest deleted events of user with their history while trash is bigger than limit of rules,
+// so deleting does not let user keep more than quota in memory. Must be called under lock after change is saved
+func (e *EventStorage) purgeTrash(userID int) {
+	user := userKey(userID)
+
+	limit := e.trashLimit()
+	for limit > 0 && len(e.trashOrder[user]) > limit {
+		id := e.trashOrder[user][0]
+		e.untrash(userID, int(id))
+		delete(e.history, eventRef{owner: user, id: id})
+	}
+}
+
+// trashed - returns deleted events of all users, events of every user go in order of deletion.
+// Must be called under lock
+func (e *EventStorage) trashed() []model.Event {
+	var events []model.Event
+	for user, order := range e.trashOrder {
+		for _, id := range order {
+			events = append(events, e.trash[user][id])
+		}
+	}
+	return events
+}
+
+// audits - returns histories of all events, must be called under lock
+func (e *EventStorage) audits() []model.AuditRecord {
+	var records []model.AuditRecord
+	for _, history := range e.history {
+		records = append(records, history...)
+	}
+	return records
+}
+
+// restoreAudits - puts records into histories of their events in given order, must be called under lock
+func (e *EventStorage) restoreAudits(records []model.AuditRecord) {
+	for _, record := range records {
+		e.remember(record)
+	}
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"main.go/internal/model"
+	"sort"
+	"time"
+)
+
+// indexEntry - position of single event in index by start
+type indexEntry struct {
+	start time.Time
+	id    eventKey
+}
+
+// calendar - all events of one user with index by start and index of words
+type calendar struct {
+	events map[eventKey]model.Event
+	// byStart - single events sorted by start and id
+	byStart []indexEntry
+	// recurring - recurring events, they have no single start and are expanded on every query
+	recurring map[eventKey]struct{}
+	// maxDuration - the longest single event ever put, nothing started earlier than this before window lasts in it
+	maxDuration time.Duration
+	// text - words of titles and descriptions
+	text *textIndex
+}
+
+// newCalendar - creates empty calendar
+func newCalendar() *calendar {
+	return &calendar{
+		events:    make(map[eventKey]model.Event),
+		recurring: make(map[eventKey]struct{}),
+		text:      newTextIndex(),
+	}
+}
+
+// put - adds or replaces event
+func (c *calendar) put(event model.Event) {
+	id := eventKey(event.EventID)
+
+	c.remove(id)
+	c.events[id] = event
+	c.text.add(event)
+
+	if event.Recurrence != nil {
+		c.recurring[id] = struct{}{}
+		return
+	}
+
+	if d := event.Duration(); d > c.maxDuration {
+		c.maxDuration = d
+	}
+
+	entry := indexEntry{start: event.Date.Time, id: id}
+	i := sort.Search(len(c.byStart), func(i int) bool {
+		return entry.less(c.byStart[i])
+	})
+
+	c.byStart = append(c.byStart, indexEntry{})
+	copy(c.byStart[i+1:], c.byStart[i:])
+	c.byStart[i] = entry
+}
+
+// remove - removes event if it exists
+func (c *calendar) remove(id eventKey) {
+	event, ok := c.events[id]
+	if !ok {
+		return
+	}
+
+	delete(c.events, id)
+	c.text.remove(event)
+
+	if event.Recurrence != nil {
+		delete(c.recurring, id)
+		return
+	}
+
+	entry := indexEntry{start: event.Date.Time, id: id}
+	i := sort.Search(len(c.byStart), func(i int) bool {
+		return !c.byStart[i].less(entry)
+	})
+	if i < len(c.byStart) && c.byStart[i].id == id {
+		c.byStart = append(c.byStart[:i], c.byStart[i+1:]...)
+	}
+}
+
+// between - returns occurrences which overlap [from, to) and go after cursor, sorted by start, id and owner.
+// Zero limit means no limit
+func (c *calendar) between(from, to time.Time, after model.Cursor, limit int) []model.Event {
+	var result []model.Event
+
+	// single events are read from index, only events started not long before window may overlap it
+	lower := from.Add(-c.maxDuration)
+	if after.Date.After(lower) {
+		lower = after.Date
+	}
+
+	i := sort.Search(len(c.byStart), func(i int) bool {
+		return !c.byStart[i].start.Before(lower)
+	})
+
+	for ; i < len(c.byStart) && c.byStart[i].start.Before(to); i++ {
+		entry := c.byStart[i]
+		event := c.events[entry.id]
+		if !after.Before(entry.start, event.EventID, event.UserID) {
+			continue
+		}
+
+		if len(event.Occurrences(from, to)) == 0 {
+			continue
+		}
+
+		result = append(result, event)
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+
+	// occurrences started after cursor date surely overlap window
+	expandFrom := from
+	if after.Date.After(from) {
+		expandFrom = after.Date
+	}
+
+	for id := range c.recurring {
+		event := c.events[id]
+
+		var n int
+		for _, occurrence := range event.Occurrences(expandFrom, to) {
+			if !after.Before(occurrence, event.EventID, event.UserID) {
+				continue
+			}
+
+			result = append(result, occurrenceOf(event, occurrence))
+			n++
+			if limit > 0 && n == limit {
+				break
+			}
+		}
+	}
+
+	sortEvents(result)
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
+
+// less - compares entries by start and id
+func (a indexEntry) less(b indexEntry) bool {
+	if !a.start.Equal(b.start) {
+		return a.start.Before(b.start)
+	}
+	return a.id < b.id
+}
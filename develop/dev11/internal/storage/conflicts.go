@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"main.go/internal/model"
+	"time"
+)
+
+// GetConflicts - returns occurrences of user events which overlap [from, to), event with excludeID is skipped
+func (e *EventStorage) GetConflicts(userID int, from, to time.Time, excludeID int) ([]model.Event, error) {
+	var conflicts []model.Event
+
+	e.RLock()
+
+	for _, event := range e.userEvents(userID) {
+		if event.EventID == excludeID {
+			continue
+		}
+
+		duration := event.Duration()
+		for _, occurrence := range event.Occurrences(from, to) {
+			if model.Overlaps(from, to, occurrence, occurrence.Add(duration)) {
+				conflicts = append(conflicts, occurrenceOf(event, occurrence))
+			}
+		}
+	}
+
+	e.RUnlock()
+
+	sortEvents(conflicts)
+
+	return conflicts, nil
+}
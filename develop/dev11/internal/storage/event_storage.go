@@ -4,125 +4,522 @@ import (
 	"errors"
 	"fmt"
 	"main.go/internal/model"
+	"main.go/internal/stream"
+	"maps"
+	"sort"
 	"sync"
 	"time"
 )
 
+// Journal - receives every change before it is applied to the data store
+type Journal interface {
+	Put(event model.Event, audit model.AuditRecord) error
+	Delete(userID, eventID int, audit model.AuditRecord) error
+	// Batch - writes changes of transaction at once, after crash all of them are restored or none
+	Batch(records []JournalRecord) error
+}
+
+// userKey - key of user calendar in data store
+type userKey int
+
+// eventKey - key of event inside user calendar
+type eventKey int
+
 // EventStorage - database structure
 type EventStorage struct {
 	sync.RWMutex
-	db map[string]model.Event
+	db map[userKey]*calendar
+	// lastID - the biggest event id ever used by user, it never decreases so deleted ids are not reused
+	lastID map[userKey]eventKey
+	// invited - events every user is invited to
+	invited map[userKey]map[eventRef]struct{}
+	// trash - deleted events of every user, they can be restored
+	trash map[userKey]map[eventKey]model.Event
+	// trashOrder - ids of deleted events of every user from the earliest deleted one
+	trashOrder map[userKey][]eventKey
+	// history - changes of every event, deleted events keep it
+	history map[eventRef][]model.AuditRecord
+	journal Journal
+	// broker - receives every applied change, may be nil
+	broker *stream.Broker
+
+	// rules - business rules checked before event is stored, may be nil
+	rules model.Rules
+}
+
+// Option - optional behaviour of data store
+type Option func(e *EventStorage)
+
+// WithRules - checks every created, restored and replaced event by rules, trash of user keeps
+// as many deleted events as rules allow
+func WithRules(r model.Rules) Option {
+	return func(e *EventStorage) {
+		e.rules = r
+	}
+}
+
+// WithBroker - publishes every change of events to broker
+func WithBroker(b *stream.Broker) Option {
+	return func(e *EventStorage) {
+		e.broker = b
+	}
 }
 
 // NewEventStorage - creates new database instance
-func NewEventStorage() *EventStorage {
-	return &EventStorage{
-		db: make(map[string]model.Event),
+func NewEventStorage(opts ...Option) *EventStorage {
+	e := &EventStorage{
+		db:         make(map[userKey]*calendar),
+		lastID:     make(map[userKey]eventKey),
+		invited:    make(map[userKey]map[eventRef]struct{}),
+		trash:      make(map[userKey]map[eventKey]model.Event),
+		trashOrder: make(map[userKey][]eventKey),
+		history:    make(map[eventRef][]model.AuditRecord),
+	}
+
+	for _, opt := range opts {
+		opt(e)
 	}
+
+	return e
 }
 
-// CreateEvent - creating new event in data store
+// CreateEvent - creating new event in data store.
+// Event without id gets the next id of its owner, assigned id is written to event.
+// Attendees have not answered yet
 func (e *EventStorage) CreateEvent(event *model.Event) error {
-	id := fmt.Sprintf("%d%d", event.UserID, event.EventID)
+	e.Lock()
+	defer e.Unlock()
 
+	return e.create(event)
+}
+
+// UpdateEvent - updating event in data store, answers of attendees invited before are kept.
+// Version of newEvent should be the current one, zero version replaces event unconditionally.
+// New version is written to newEvent
+func (e *EventStorage) UpdateEvent(userID, eventID int, newEvent *model.Event) error {
 	e.Lock()
+	defer e.Unlock()
+
+	return e.update(userID, eventID, newEvent)
+}
 
-	if _, ok := e.db[id]; ok {
-		e.Unlock()
-		return errors.New("event with such id already exist")
+// DeleteEvent - moves event to trash and returns it. Version should be the current one of event,
+// zero version deletes event unconditionally
+func (e *EventStorage) DeleteEvent(userID, eventID, version int) (model.Event, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	return e.delete(userID, eventID, version)
+}
+
+// create - saves new event and writes assigned id back, must be called under lock
+func (e *EventStorage) create(event *model.Event) error {
+	newEvent, err := e.prepareCreate(*event)
+	if err != nil {
+		return err
+	}
+
+	audit := newAudit(model.ActionCreate, newEvent.UserID, nil, &newEvent)
+	if err := e.journalPut(newEvent, audit); err != nil {
+		return err
 	}
-	e.db[id] = *event
+	e.restore(newEvent)
+	e.remember(audit)
+	e.publish(stream.Create, newEvent, newEvent)
 
-	e.Unlock()
+	event.EventID = newEvent.EventID
+	event.Attendees = newEvent.Attendees
+	event.Version = newEvent.Version
 
 	return nil
 }
 
-// UpdateEvent - updating event in data store
-func (e *EventStorage) UpdateEvent(userID, eventID int, newEvent *model.Event) error {
-	combinedID := fmt.Sprintf("%d%d", userID, eventID)
+// update - replaces event and writes new version back, must be called under lock
+func (e *EventStorage) update(userID, eventID int, newEvent *model.Event) error {
+	old, err := e.prepareUpdate(userID, eventID, newEvent)
+	if err != nil {
+		return err
+	}
 
-	e.Lock()
+	audit := newAudit(model.ActionUpdate, userID, &old, newEvent)
+	if err := e.journalPut(*newEvent, audit); err != nil {
+		return err
+	}
+	e.restore(*newEvent)
+	e.remember(audit)
+	e.publish(stream.Update, old, *newEvent)
 
-	if _, ok := e.db[combinedID]; !ok {
-		e.Unlock()
-		return fmt.Errorf("there is no event with id: %s", combinedID)
+	return nil
+}
+
+// delete - moves event to trash and returns it, must be called under lock
+func (e *EventStorage) delete(userID, eventID, version int) (model.Event, error) {
+	old, err := e.prepareDelete(userID, eventID, version)
+	if err != nil {
+		return model.Event{}, err
 	}
 
-	e.db[combinedID] = *newEvent
+	audit := newAudit(model.ActionDelete, userID, &old, nil)
+	if err := e.journalDelete(userID, eventID, audit); err != nil {
+		return model.Event{}, fmt.Errorf("event %d of user %d is not deleted: %v", eventID, userID, err)
+	}
+	e.discard(old)
+	e.remember(audit)
+	e.purgeTrash(userID)
+	e.publish(stream.Delete, old, old)
 
-	e.Unlock()
+	return old, nil
+}
 
-	return nil
+// prepareCreate - returns event as it will be stored or error if it can not be created,
+// must be called under lock
+func (e *EventStorage) prepareCreate(newEvent model.Event) (model.Event, error) {
+	if newEvent.EventID == 0 {
+		newEvent.EventID = int(e.lastID[userKey(newEvent.UserID)]) + 1
+	}
+	newEvent.Attendees = keepAnswers(model.Event{}, newEvent.Attendees)
+	newEvent.Version = 1
+
+	// deleted event keeps its id until it is restored
+	_, inTrash := e.trash[userKey(newEvent.UserID)][eventKey(newEvent.EventID)]
+	if _, ok := e.lookup(newEvent.UserID, newEvent.EventID); ok || inTrash {
+		return model.Event{}, errors.New("event with such id already exist")
+	}
+
+	if err := e.checkRules(newEvent, true); err != nil {
+		return model.Event{}, err
+	}
+
+	return newEvent, nil
 }
 
-// DeleteEvent - deleting event from data store
-func (e *EventStorage) DeleteEvent(userID, eventID int) {
+// prepareUpdate - checks that event can be replaced and fills attendees and version of newEvent,
+// returns stored event. Must be called under lock
+func (e *EventStorage) prepareUpdate(userID, eventID int, newEvent *model.Event) (model.Event, error) {
+	if newEvent.UserID != userID || newEvent.EventID != eventID {
+		return model.Event{}, fmt.Errorf("event %d of user %d can not be replaced with event %d of user %d",
+			eventID, userID, newEvent.EventID, newEvent.UserID)
+	}
+
+	old, ok := e.lookup(userID, eventID)
+	if !ok {
+		return model.Event{}, &model.NotFoundError{UserID: userID, EventID: eventID}
+	}
 
-	id := fmt.Sprintf("%d%d", userID, eventID)
+	if newEvent.Version != 0 && newEvent.Version != old.Version {
+		return model.Event{}, &model.VersionConflictError{UserID: userID, EventID: eventID, Version: old.Version}
+	}
 
-	e.Lock()
+	updated := *newEvent
+	updated.Attendees = keepAnswers(old, updated.Attendees)
+	updated.Version = old.Version + 1
+
+	if err := e.checkRules(updated, false); err != nil {
+		return model.Event{}, err
+	}
 
-	delete(e.db, id)
+	*newEvent = updated
 
-	e.Unlock()
+	return old, nil
+}
+
+// prepareDelete - returns stored event and checks its version, must be called under lock
+func (e *EventStorage) prepareDelete(userID, eventID, version int) (model.Event, error) {
+	old, ok := e.lookup(userID, eventID)
+	if !ok {
+		return model.Event{}, &model.NotFoundError{UserID: userID, EventID: eventID}
+	}
+
+	if version != 0 && version != old.Version {
+		return model.Event{}, &model.VersionConflictError{UserID: userID, EventID: eventID, Version: old.Version}
+	}
+
+	return old, nil
 }
 
 // GetEventsForWeek - returns all events for current week
 func (e *EventStorage) GetEventsForWeek(date time.Time, userID int) ([]model.Event, error) {
-	var eventsForWeek []model.Event
+	y, m, d := date.Date()
+	// ISO weeks start on Monday
+	from := time.Date(y, m, d-(int(date.Weekday())+6)%7, 0, 0, 0, 0, date.Location())
 
-	currYear, currWeek := date.ISOWeek()
+	return e.eventsBetween(userID, from, from.AddDate(0, 0, 7)), nil
+}
 
+// GetEventsForDay - returns all events for current day
+func (e *EventStorage) GetEventsForDay(date time.Time, userID int) ([]model.Event, error) {
+	y, m, d := date.Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+
+	return e.eventsBetween(userID, from, from.AddDate(0, 0, 1)), nil
+}
+
+// GetEventsForMonth - returns all events for current month
+func (e *EventStorage) GetEventsForMonth(date time.Time, userID int) ([]model.Event, error) {
+	y, m, _ := date.Date()
+	from := time.Date(y, m, 1, 0, 0, 0, 0, date.Location())
+
+	return e.eventsBetween(userID, from, from.AddDate(0, 1, 0)), nil
+}
+
+// GetEvent - returns stored event of user, recurring event is not expanded
+func (e *EventStorage) GetEvent(userID, eventID int) (model.Event, error) {
 	e.RLock()
-	for _, event := range e.db {
-		eventYear, eventWeek := event.Date.ISOWeek()
-		time.Now().ISOWeek()
-		if eventYear == currYear && eventWeek == currWeek && userID == event.UserID {
-			eventsForWeek = append(eventsForWeek, event)
+	event, ok := e.lookup(userID, eventID)
+	e.RUnlock()
+
+	if !ok {
+		return model.Event{}, &model.NotFoundError{UserID: userID, EventID: eventID}
+	}
+
+	return event, nil
+}
+
+// Size - returns number of users with events and number of stored events
+func (e *EventStorage) Size() (users, events int) {
+	e.RLock()
+	defer e.RUnlock()
+
+	for _, cal := range e.db {
+		if len(cal.events) > 0 {
+			users++
 		}
+		events += len(cal.events)
 	}
+
+	return users, events
+}
+
+// GetEvents - returns all events of user as they are stored, recurring events are not expanded
+func (e *EventStorage) GetEvents(userID int) ([]model.Event, error) {
+	e.RLock()
+
+	userEvents := e.userEvents(userID)
+	events := make([]model.Event, 0, len(userEvents))
+	for _, event := range userEvents {
+		events = append(events, event)
+	}
+
 	e.RUnlock()
 
-	return eventsForWeek, nil
+	sortEvents(events)
+
+	return events, nil
 }
 
-// GetEventsForDay - returns all events for current day
-func (e *EventStorage) GetEventsForDay(date time.Time, userID int) ([]model.Event, error) {
-	var eventsForDay []model.Event
+// GetEventsInRange - returns up to limit occurrences of user events and events user is invited to
+// which overlap [from, to) and go after cursor, sorted by date and id. Zero limit means no limit
+func (e *EventStorage) GetEventsInRange(userID int, from, to time.Time, after model.Cursor, limit int) ([]model.Event, error) {
+	e.RLock()
+	defer e.RUnlock()
 
-	y, m, d := date.Date()
+	var events []model.Event
+	if cal, ok := e.db[userKey(userID)]; ok {
+		events = cal.between(from, to, after, limit)
+	}
+
+	invited := e.invitedBetween(userID, from, to, after)
+	if len(invited) == 0 {
+		return events, nil
+	}
+
+	events = append(events, invited...)
+	sortEvents(events)
 
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+// GetOccurrencesStarting - returns occurrences of events of all users which start in [from, to)
+func (e *EventStorage) GetOccurrencesStarting(from, to time.Time) ([]model.Event, error) {
 	e.RLock()
+	defer e.RUnlock()
+
+	var events []model.Event
+	for _, cal := range e.db {
+		for _, event := range cal.between(from, to, model.Cursor{}, 0) {
+			// between returns also occurrences started before window
+			if !event.Date.Before(from) {
+				events = append(events, event)
+			}
+		}
+	}
+
+	sortEvents(events)
+
+	return events, nil
+}
+
+// eventsBetween - returns events of user which overlap [from, to) sorted by date,
+// recurring events are expanded into occurrences
+func (e *EventStorage) eventsBetween(userID int, from, to time.Time) []model.Event {
+	events, _ := e.GetEventsInRange(userID, from, to, model.Cursor{}, 0)
+	return events
+}
+
+// occurrenceOf - returns copy of event starting at occurrence
+func occurrenceOf(event model.Event, occurrence time.Time) model.Event {
+	if event.Recurrence == nil {
+		return event
+	}
 
-	for _, event := range e.db {
-		eventY, eventM, eventD := event.Date.Date()
-		if y == eventY && int(eventM) == int(m) && d == eventD && userID == event.UserID {
-			eventsForDay = append(eventsForDay, event)
+	seriesDate := event.Date
+	event.SeriesDate = &seriesDate
+	event.Date = model.Date{Time: occurrence}
+
+	return event
+}
+
+// sortEvents - sorts events by date, id and owner
+func sortEvents(events []model.Event) {
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].Date.Equal(events[j].Date.Time) {
+			return events[i].Date.Before(events[j].Date.Time)
+		}
+		if events[i].EventID != events[j].EventID {
+			return events[i].EventID < events[j].EventID
 		}
+		return events[i].UserID < events[j].UserID
+	})
+}
+
+// checkRules - checks event by rules before it is stored, added is true for created or restored event.
+// Must be called under lock
+func (e *EventStorage) checkRules(event model.Event, added bool) error {
+	if e.rules == nil {
+		return nil
 	}
 
-	e.RUnlock()
+	stored := e.userEvents(event.UserID)
+	return e.rules.CheckPut(event, maps.Values(stored), len(stored), added)
+}
 
-	return eventsForDay, nil
+// trashLimit - returns how many deleted events of one user are kept, zero means no limit
+func (e *EventStorage) trashLimit() int {
+	if e.rules == nil {
+		return 0
+	}
+	return e.rules.TrashLimit()
 }
 
-// GetEventsForMonth - returns all events for current month
-func (e *EventStorage) GetEventsForMonth(date time.Time, userID int) ([]model.Event, error) {
-	var eventsForMonth []model.Event
+// userEvents - returns events of user by id, nil for user without events, must be called under lock
+func (e *EventStorage) userEvents(userID int) map[eventKey]model.Event {
+	cal, ok := e.db[userKey(userID)]
+	if !ok {
+		return nil
+	}
+	return cal.events
+}
 
-	y, m, _ := date.Date()
+// lookup - finds event of user, must be called under lock
+func (e *EventStorage) lookup(userID, eventID int) (model.Event, bool) {
+	// reading from missing calendar is safe, it is nil map
+	event, ok := e.userEvents(userID)[eventKey(eventID)]
+	return event, ok
+}
 
-	e.RLock()
+// restore - puts event into calendar of its owner bypassing journal, must be called under lock
+func (e *EventStorage) restore(event model.Event) {
+	// events saved before versioning get the first version
+	if event.Version == 0 {
+		event.Version = 1
+	}
+
+	cal, ok := e.db[userKey(event.UserID)]
+	if !ok {
+		cal = newCalendar()
+		e.db[userKey(event.UserID)] = cal
+	}
+
+	if old, ok := cal.events[eventKey(event.EventID)]; ok {
+		e.unindexAttendees(old)
+	}
+	cal.put(event)
+	e.indexAttendees(event)
+	e.untrash(event.UserID, event.EventID)
+
+	if e.lastID[userKey(event.UserID)] < eventKey(event.EventID) {
+		e.lastID[userKey(event.UserID)] = eventKey(event.EventID)
+	}
+}
+
+// forget - removes event from calendar bypassing journal, must be called under lock
+func (e *EventStorage) forget(userID, eventID int) {
+	cal, ok := e.db[userKey(userID)]
+	if !ok {
+		return
+	}
+
+	if old, ok := cal.events[eventKey(eventID)]; ok {
+		e.unindexAttendees(old)
+	}
+	cal.remove(eventKey(eventID))
+	if len(cal.events) == 0 {
+		delete(e.db, userKey(userID))
+	}
+}
 
-	for _, event := range e.db {
-		eventY, eventM, _ := event.Date.Date()
-		if y == eventY && int(eventM) == int(m) && userID == event.UserID {
-			eventsForMonth = append(eventsForMonth, event)
+// events - returns all stored events, must be called under lock
+func (e *EventStorage) events() []model.Event {
+	var events []model.Event
+	for _, cal := range e.db {
+		for _, event := range cal.events {
+			events = append(events, event)
 		}
 	}
+	return events
+}
 
-	e.RUnlock()
+// lastIDs - returns the biggest used event id of every user, must be called under lock
+func (e *EventStorage) lastIDs() map[int]int {
+	ids := make(map[int]int, len(e.lastID))
+	for user, id := range e.lastID {
+		ids[int(user)] = int(id)
+	}
+	return ids
+}
+
+// restoreLastIDs - sets the biggest used event ids, must be called under lock
+func (e *EventStorage) restoreLastIDs(ids map[int]int) {
+	for user, id := range ids {
+		if e.lastID[userKey(user)] < eventKey(id) {
+			e.lastID[userKey(user)] = eventKey(id)
+		}
+	}
+}
+
+// publish - sends change to broker, recipients are owner and attendees of event before and after change.
+// Must be called under lock, so changes are published in order they are applied
+func (e *EventStorage) publish(changeType string, old, event model.Event) {
+	if e.broker == nil {
+		return
+	}
 
-	return eventsForMonth, nil
+	users := []int{event.UserID}
+	for _, events := range [][]model.Attendee{old.Attendees, event.Attendees} {
+		for _, attendee := range events {
+			users = append(users, attendee.UserID)
+		}
+	}
+
+	e.broker.Publish(changeType, event, users)
+}
+
+// journalPut - writes event to journal if data store has one, must be called under lock
+func (e *EventStorage) journalPut(event model.Event, audit model.AuditRecord) error {
+	if e.journal == nil {
+		return nil
+	}
+	return e.journal.Put(event, audit)
+}
+
+// journalDelete - writes deletion to journal if data store has one, must be called under lock
+func (e *EventStorage) journalDelete(userID, eventID int, audit model.AuditRecord) error {
+	if e.journal == nil {
+		return nil
+	}
+	return e.journal.Delete(userID, eventID, audit)
 }
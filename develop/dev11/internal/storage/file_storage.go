@@ -0,0 +1,428 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"main.go/internal/config/helper"
+	"main.go/internal/model"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	walFile      = "wal.log"
+
+	opPut    = "put"
+	opDelete = "delete"
+	opBatch  = "batch"
+)
+
+// walRecord - single line of write-ahead log
+type walRecord struct {
+	Op      string       `json:"op"`
+	UserID  int          `json:"user_id,omitempty"`
+	EventID int          `json:"event_id,omitempty"`
+	Event   *model.Event `json:"event,omitempty"`
+	// Audit - history record of change, records written before audit have none
+	Audit *model.AuditRecord `json:"audit,omitempty"`
+	// Records - changes of transaction, they are in one line so they are written at once
+	Records []walRecord `json:"records,omitempty"`
+}
+
+// snapshot - full state of data store saved on disk
+type snapshot struct {
+	Events []model.Event `json:"events"`
+	// LastIDs - the biggest event id of every user, deleted events do not keep it
+	LastIDs map[int]int `json:"last_ids,omitempty"`
+	// Trash - deleted events which can be restored
+	Trash []model.Event `json:"trash,omitempty"`
+	// History - changes of events, records of every event go from the oldest one
+	History []model.AuditRecord `json:"history,omitempty"`
+}
+
+// logFile - file under write-ahead log
+type logFile interface {
+	io.ReadWriteCloser
+	io.Seeker
+	Sync() error
+	Truncate(size int64) error
+}
+
+// writeAheadLog - journal which appends every change to file
+type writeAheadLog struct {
+	file    logFile
+	records int
+}
+
+// FileStorage - event storage which survives restarts:
+// every change is appended to write-ahead log and the whole state is periodically saved as snapshot
+type FileStorage struct {
+	*EventStorage
+	dir string
+	wal *writeAheadLog
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// OpenFileStorage - opens data store in dir, restoring state left by previous run.
+// Snapshot is taken every snapshotInterval, zero interval disables periodic snapshots
+func OpenFileStorage(dir string, snapshotInterval time.Duration, opts ...Option) (*FileStorage, error) {
+	err := os.MkdirAll(dir, 0750)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FileStorage{
+		EventStorage: NewEventStorage(opts...),
+		dir:          dir,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	err = f.loadSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("error while loading snapshot: %v", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, err
+	}
+	f.wal = &writeAheadLog{file: file}
+
+	err = f.replay()
+	if err != nil {
+		helper.Closer(file)
+		return nil, fmt.Errorf("error while replaying wal: %v", err)
+	}
+
+	f.journal = f.wal
+
+	if snapshotInterval > 0 {
+		go f.snapshotLoop(snapshotInterval)
+	} else {
+		close(f.done)
+	}
+
+	return f, nil
+}
+
+// Snapshot - saves the whole state on disk and truncates write-ahead log
+func (f *FileStorage) Snapshot() error {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.snapshot()
+}
+
+// Close - stops periodic snapshots, saves final snapshot and closes write-ahead log
+func (f *FileStorage) Close() error {
+	var err error
+
+	f.closeOnce.Do(func() {
+		close(f.stop)
+		<-f.done
+
+		f.Lock()
+		err = f.snapshot()
+		if closeErr := f.wal.file.Close(); err == nil {
+			err = closeErr
+		}
+		f.Unlock()
+	})
+
+	return err
+}
+
+// snapshot - writes state into temporary file and atomically replaces previous snapshot, must be called under lock
+func (f *FileStorage) snapshot() error {
+	if f.wal.records == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(snapshot{
+		Events:  f.events(),
+		LastIDs: f.lastIDs(),
+		Trash:   f.trashed(),
+		History: f.audits(),
+	})
+	if err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(f.dir, snapshotFile+".tmp")
+
+	err = writeFileSync(tmpPath, data)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, filepath.Join(f.dir, snapshotFile))
+	if err != nil {
+		return err
+	}
+
+	err = syncDir(f.dir)
+	if err != nil {
+		return err
+	}
+
+	// everything from wal is in snapshot now
+	return f.wal.truncate(0)
+}
+
+// snapshotLoop - takes snapshot every interval until storage is closed
+func (f *FileStorage) snapshotLoop(interval time.Duration) {
+	defer close(f.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			if err := f.Snapshot(); err != nil {
+				log.Printf("error while taking snapshot: %v", err)
+			}
+		}
+	}
+}
+
+// loadSnapshot - restores state from snapshot file if it exists
+func (f *FileStorage) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(f.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+
+	err = json.Unmarshal(data, &snap)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range snap.Events {
+		f.restore(event)
+	}
+	for _, event := range snap.Trash {
+		f.discard(event)
+	}
+	f.restoreLastIDs(snap.LastIDs)
+	f.restoreAudits(snap.History)
+
+	return nil
+}
+
+// replay - applies write-ahead log on top of snapshot.
+// Incomplete record at the end of log is left by crash during write, it is cut off
+func (f *FileStorage) replay() error {
+	reader := bufio.NewReader(f.wal.file)
+
+	var (
+		offset  int64
+		records int
+	)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				log.Printf("dropping incomplete wal record at offset %d", offset)
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var rec walRecord
+
+		err = json.Unmarshal(bytes.TrimSpace(line), &rec)
+		if err != nil {
+			// broken record is allowed only as the last one
+			if _, peekErr := reader.Peek(1); !errors.Is(peekErr, io.EOF) {
+				return fmt.Errorf("corrupted record at offset %d: %v", offset, err)
+			}
+			log.Printf("dropping broken wal record at offset %d: %v", offset, err)
+			break
+		}
+
+		if !f.replayRecord(rec) {
+			return fmt.Errorf("unknown record at offset %d: %s", offset, line)
+		}
+
+		offset += int64(len(line))
+		records++
+	}
+
+	err := f.wal.truncate(offset)
+	if err != nil {
+		return err
+	}
+
+	// replayed records are not in snapshot yet
+	f.wal.records = records
+
+	return nil
+}
+
+// replayRecord - applies record of write-ahead log, returns false for unknown record
+func (f *FileStorage) replayRecord(rec walRecord) bool {
+	switch {
+	case rec.Op == opPut && rec.Event != nil:
+		f.restore(*rec.Event)
+	case rec.Op == opDelete:
+		if old, ok := f.lookup(rec.UserID, rec.EventID); ok {
+			f.discard(old)
+		}
+	case rec.Op == opBatch:
+		for _, sub := range rec.Records {
+			if sub.Op == opBatch || !f.replayRecord(sub) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+
+	if rec.Audit != nil {
+		f.remember(*rec.Audit)
+	}
+	if rec.Op == opDelete {
+		f.purgeTrash(rec.UserID)
+	}
+
+	return true
+}
+
+// Put - appends new state of event to write-ahead log
+func (w *writeAheadLog) Put(event model.Event, audit model.AuditRecord) error {
+	return w.append(walRecord{Op: opPut, Event: &event, Audit: &audit})
+}
+
+// Delete - appends deletion of event to write-ahead log
+func (w *writeAheadLog) Delete(userID, eventID int, audit model.AuditRecord) error {
+	return w.append(walRecord{Op: opDelete, UserID: userID, EventID: eventID, Audit: &audit})
+}
+
+// Batch - appends changes of transaction to write-ahead log as one record
+func (w *writeAheadLog) Batch(records []JournalRecord) error {
+	rec := walRecord{Op: opBatch, Records: make([]walRecord, len(records))}
+
+	for i, r := range records {
+		audit := r.Audit
+		if r.Event == nil {
+			rec.Records[i] = walRecord{Op: opDelete, UserID: r.UserID, EventID: r.EventID, Audit: &audit}
+		} else {
+			rec.Records[i] = walRecord{Op: opPut, Event: r.Event, Audit: &audit}
+		}
+	}
+
+	return w.append(rec)
+}
+
+// append - writes record to the end of log and syncs it to disk.
+// Failed record is cut off, so torn bytes do not stop replay on the next start
+func (w *writeAheadLog) append(rec walRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	offset, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("error while writing wal: %v", err)
+	}
+
+	_, err = w.file.Write(append(line, '\n'))
+	if err != nil {
+		return w.rollback(offset, fmt.Errorf("error while writing wal: %v", err))
+	}
+
+	err = w.file.Sync()
+	if err != nil {
+		return w.rollback(offset, fmt.Errorf("error while syncing wal: %v", err))
+	}
+
+	w.records++
+
+	return nil
+}
+
+// rollback - cuts off record written from offset after err
+func (w *writeAheadLog) rollback(offset int64, err error) error {
+	truncErr := w.truncate(offset)
+	if truncErr != nil {
+		return fmt.Errorf("%v, record is not cut off: %v", err, truncErr)
+	}
+
+	return err
+}
+
+// truncate - cuts log to size bytes and moves write position to its end
+func (w *writeAheadLog) truncate(size int64) error {
+	err := w.file.Truncate(size)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.file.Seek(size, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	if size == 0 {
+		w.records = 0
+	}
+
+	return nil
+}
+
+// writeFileSync - writes data to file and flushes it to disk
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// syncDir - flushes directory entries, so renamed file survives power loss
+func syncDir(dir string) error {
+	d, err := os.Open(filepath.Clean(dir))
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"errors"
+	"main.go/internal/model"
+	"os"
+	"testing"
+	"time"
+)
+
+// failingFile - log file which tears writes or fails sync
+type failingFile struct {
+	*os.File
+	tornWrite  bool
+	failedSync bool
+}
+
+func (f *failingFile) Write(p []byte) (int, error) {
+	if f.tornWrite {
+		n, _ := f.File.Write(p[:len(p)/2])
+		return n, errors.New("no space left on device")
+	}
+
+	return f.File.Write(p)
+}
+
+func (f *failingFile) Sync() error {
+	if f.failedSync {
+		return errors.New("input/output error")
+	}
+
+	return f.File.Sync()
+}
+
+func TestWALFailedAppend(t *testing.T) {
+	tests := []struct {
+		name string
+		file failingFile
+	}{
+		{name: "torn write", file: failingFile{tornWrite: true}},
+		{name: "failed sync", file: failingFile{failedSync: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			date := model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)}
+
+			store, err := OpenFileStorage(dir, 0)
+			if err != nil {
+				t.Fatalf("cannot open storage: %v", err)
+			}
+			if err := store.CreateEvent(&model.Event{UserID: 1, Title: "before", Date: date}); err != nil {
+				t.Fatalf("cannot create event: %v", err)
+			}
+
+			file := tt.file
+			file.File = store.wal.file.(*os.File)
+			store.wal.file = &file
+
+			if err := store.CreateEvent(&model.Event{UserID: 1, Title: "failed", Date: date}); err == nil {
+				t.Fatalf("failed append should return error")
+			}
+
+			// log keeps working after failure
+			store.wal.file = file.File
+			if err := store.CreateEvent(&model.Event{UserID: 1, Title: "after", Date: date}); err != nil {
+				t.Fatalf("cannot create event after failed append: %v", err)
+			}
+
+			// store is dropped without Close, as if process was killed
+			reopened, err := OpenFileStorage(dir, 0)
+			if err != nil {
+				t.Fatalf("cannot reopen storage after failed append: %v", err)
+			}
+			defer reopened.Close()
+
+			events, _ := reopened.GetEvents(1)
+			if len(events) != 2 || events[0].Title != "before" || events[1].Title != "after" {
+				t.Errorf("wrong events after reopen\nShould: before, after\nGot: %v", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"main.go/internal/model"
+	"time"
+)
+
+// GetBusy - returns merged intervals of [from, to) taken by occurrences of user events
+// and events user is invited to and has not declined. Events without duration take no time
+func (e *EventStorage) GetBusy(userID int, from, to time.Time) ([]model.Interval, error) {
+	events, err := e.GetEventsInRange(userID, from, to, model.Cursor{}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make([]model.Interval, 0, len(events))
+	for _, event := range events {
+		duration := event.Duration()
+		if duration == 0 {
+			continue
+		}
+
+		start, end := event.Date.Time, event.Date.Add(duration)
+		if !model.Overlaps(from, to, start, end) {
+			continue
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+
+		busy = append(busy, model.Interval{Start: start, End: end})
+	}
+
+	return model.MergeIntervals(busy), nil
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"main.go/internal/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+// textIndex - inverted index of words of event titles and descriptions of one user
+type textIndex struct {
+	// postings - events having word
+	postings map[string]map[eventKey]struct{}
+	// words - indexed words sorted, words with common prefix go together
+	words []string
+}
+
+// newTextIndex - creates empty index
+func newTextIndex() *textIndex {
+	return &textIndex{postings: make(map[string]map[eventKey]struct{})}
+}
+
+// add - indexes words of event
+func (t *textIndex) add(event model.Event) {
+	id := eventKey(event.EventID)
+
+	for _, word := range event.Terms() {
+		ids, ok := t.postings[word]
+		if !ok {
+			ids = make(map[eventKey]struct{})
+			t.postings[word] = ids
+
+			i := sort.SearchStrings(t.words, word)
+			t.words = append(t.words, "")
+			copy(t.words[i+1:], t.words[i:])
+			t.words[i] = word
+		}
+		ids[id] = struct{}{}
+	}
+}
+
+// remove - drops words of event from index, words of no event are forgotten
+func (t *textIndex) remove(event model.Event) {
+	id := eventKey(event.EventID)
+
+	for _, word := range event.Terms() {
+		ids := t.postings[word]
+		delete(ids, id)
+		if len(ids) > 0 {
+			continue
+		}
+
+		delete(t.postings, word)
+		i := sort.SearchStrings(t.words, word)
+		if i < len(t.words) && t.words[i] == word {
+			t.words = append(t.words[:i], t.words[i+1:]...)
+		}
+	}
+}
+
+// prefixed - returns events having word which starts with prefix
+func (t *textIndex) prefixed(prefix string) map[eventKey]struct{} {
+	result := make(map[eventKey]struct{})
+
+	for i := sort.SearchStrings(t.words, prefix); i < len(t.words) && strings.HasPrefix(t.words[i], prefix); i++ {
+		for id := range t.postings[t.words[i]] {
+			result[id] = struct{}{}
+		}
+	}
+
+	return result
+}
+
+// search - returns events which have word for every term, term matches words it is prefix of
+func (t *textIndex) search(terms []string) map[eventKey]struct{} {
+	var result map[eventKey]struct{}
+
+	for _, term := range terms {
+		ids := t.prefixed(term)
+		if result == nil {
+			result = ids
+			continue
+		}
+
+		for id := range result {
+			if _, ok := ids[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+
+	return result
+}
+
+// SearchEvents - returns user events and events user is invited to and has not declined,
+// which have words starting with every term, sorted by date.
+// If from and to are not zero, only occurrences overlapping [from, to) are returned
+func (e *EventStorage) SearchEvents(userID int, terms []string, from, to time.Time) ([]model.Event, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	e.RLock()
+
+	var found []model.Event
+	if cal, ok := e.db[userKey(userID)]; ok {
+		for id := range cal.text.search(terms) {
+			found = append(found, cal.events[id])
+		}
+	}
+
+	// invitations are few, they are matched without index
+	for ref := range e.invited[userKey(userID)] {
+		event, ok := e.lookup(int(ref.owner), int(ref.id))
+		if !ok {
+			continue
+		}
+		if attendee, _ := event.Attendee(userID); attendee.Status == model.Declined {
+			continue
+		}
+		if event.MatchesTerms(terms) {
+			found = append(found, event)
+		}
+	}
+
+	e.RUnlock()
+
+	events := found
+	if !from.IsZero() || !to.IsZero() {
+		events = nil
+		for _, event := range found {
+			for _, occurrence := range event.Occurrences(from, to) {
+				events = append(events, occurrenceOf(event, occurrence))
+			}
+		}
+	}
+
+	sortEvents(events)
+
+	return events, nil
+}
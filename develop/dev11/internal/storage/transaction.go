@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"fmt"
+	"main.go/internal/model"
+	"main.go/internal/stream"
+)
+
+// JournalRecord - change written to journal as part of transaction, Event is nil for deletion
+type JournalRecord struct {
+	UserID  int
+	EventID int
+	Event   *model.Event
+	Audit   model.AuditRecord
+}
+
+// staged - operation of atomic transaction applied in memory but not yet written to journal
+type staged struct {
+	changeType string
+	old        model.Event
+	event      model.Event
+	record     JournalRecord
+	// undo - returns data store to state before operation
+	undo func()
+}
+
+// Transaction - applies operations in order under one lock, so no other change comes between them.
+// Atomic transaction applies all operations or none: the first failed one is returned as *model.TransactionError
+// and results of other operations have model.ErrNotApplied. Otherwise every operation is applied alone
+// and its result tells if it failed
+func (e *EventStorage) Transaction(ops []model.Operation, atomic bool) ([]model.OperationResult, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	results := make([]model.OperationResult, len(ops))
+
+	if !atomic {
+		for i, op := range ops {
+			results[i].Event, results[i].Err = e.apply(op)
+		}
+		return results, nil
+	}
+
+	var done []staged
+	for i, op := range ops {
+		s, err := e.stage(op)
+		if err != nil {
+			e.rollback(done)
+			for j := range results {
+				results[j] = model.OperationResult{Err: model.ErrNotApplied}
+			}
+			results[i] = model.OperationResult{Err: err}
+			return results, &model.TransactionError{Index: i, Err: err}
+		}
+
+		done = append(done, s)
+		results[i].Event = s.event
+	}
+
+	if e.journal != nil {
+		records := make([]JournalRecord, len(done))
+		for i, s := range done {
+			records[i] = s.record
+		}
+
+		if err := e.journal.Batch(records); err != nil {
+			e.rollback(done)
+			return nil, fmt.Errorf("transaction is not saved: %v", err)
+		}
+	}
+
+	for _, s := range done {
+		if s.changeType == stream.Delete {
+			e.purgeTrash(s.old.UserID)
+		}
+		e.publish(s.changeType, s.old, s.event)
+	}
+
+	return results, nil
+}
+
+// apply - applies single operation with its own journal record, must be called under lock
+func (e *EventStorage) apply(op model.Operation) (model.Event, error) {
+	event := op.Event
+
+	switch op.Op {
+	case model.OpCreate:
+		err := e.create(&event)
+		return event, err
+	case model.OpUpdate:
+		err := e.update(event.UserID, event.EventID, &event)
+		return event, err
+	case model.OpDelete:
+		return e.delete(event.UserID, event.EventID, event.Version)
+	default:
+		return model.Event{}, fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// stage - applies operation in memory only and returns how to undo it, must be called under lock
+func (e *EventStorage) stage(op model.Operation) (staged, error) {
+	event := op.Event
+
+	switch op.Op {
+	case model.OpCreate:
+		newEvent, err := e.prepareCreate(event)
+		if err != nil {
+			return staged{}, err
+		}
+
+		user := userKey(newEvent.UserID)
+		lastID, hadLastID := e.lastID[user]
+		audit := newAudit(model.ActionCreate, newEvent.UserID, nil, &newEvent)
+		e.restore(newEvent)
+		e.remember(audit)
+
+		return staged{
+			changeType: stream.Create,
+			old:        newEvent,
+			event:      newEvent,
+			record:     JournalRecord{UserID: newEvent.UserID, EventID: newEvent.EventID, Event: &newEvent, Audit: audit},
+			undo: func() {
+				e.forgetLast(newEvent.UserID, newEvent.EventID)
+				e.forget(newEvent.UserID, newEvent.EventID)
+				if hadLastID {
+					e.lastID[user] = lastID
+				} else {
+					delete(e.lastID, user)
+				}
+			},
+		}, nil
+
+	case model.OpUpdate:
+		old, err := e.prepareUpdate(event.UserID, event.EventID, &event)
+		if err != nil {
+			return staged{}, err
+		}
+		audit := newAudit(model.ActionUpdate, event.UserID, &old, &event)
+		e.restore(event)
+		e.remember(audit)
+
+		return staged{
+			changeType: stream.Update,
+			old:        old,
+			event:      event,
+			record:     JournalRecord{UserID: event.UserID, EventID: event.EventID, Event: &event, Audit: audit},
+			undo: func() {
+				e.forgetLast(old.UserID, old.EventID)
+				e.restore(old)
+			},
+		}, nil
+
+	case model.OpDelete:
+		old, err := e.prepareDelete(event.UserID, event.EventID, event.Version)
+		if err != nil {
+			return staged{}, err
+		}
+		audit := newAudit(model.ActionDelete, old.UserID, &old, nil)
+		e.discard(old)
+		e.remember(audit)
+
+		return staged{
+			changeType: stream.Delete,
+			old:        old,
+			event:      old,
+			record:     JournalRecord{UserID: old.UserID, EventID: old.EventID, Audit: audit},
+			undo: func() {
+				e.forgetLast(old.UserID, old.EventID)
+				e.restore(old)
+			},
+		}, nil
+
+	default:
+		return staged{}, fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// rollback - undoes staged operations in reverse order, must be called under lock
+func (e *EventStorage) rollback(done []staged) {
+	for i := len(done) - 1; i >= 0; i-- {
+		done[i].undo()
+	}
+}
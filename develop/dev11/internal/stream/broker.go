@@ -0,0 +1,209 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"main.go/internal/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of changes
+const (
+	Create = "create"
+	Update = "update"
+	Delete = "delete"
+)
+
+// DefaultHistory - number of changes kept for replay by default
+const DefaultHistory = 1000
+
+// subscriberBuffer - changes waiting for slow subscriber, it is disconnected when buffer is full
+const subscriberBuffer = 64
+
+// ErrWrongEventID - Last-Event-ID is not in form <epoch>-<id> or <id>
+var ErrWrongEventID = errors.New("wrong event id")
+
+// Change - change of event delivered to subscribers
+type Change struct {
+	// ID - sequence number of change, it grows by one with every change and starts again with broker
+	ID      uint64      `json:"id"`
+	Type    string      `json:"type"`
+	UserID  int         `json:"user_id"`
+	EventID int         `json:"event_id"`
+	Event   model.Event `json:"event"`
+	// users - owner and attendees of event who receive the change
+	users []int
+	// epoch - start of broker which numbered the change
+	epoch int64
+}
+
+// StreamID - returns id of change for event stream in form <epoch>-<id>,
+// so ids given before restart are told apart from new ones
+func (c Change) StreamID() string {
+	return fmt.Sprintf("%d-%d", c.epoch, c.ID)
+}
+
+// ParseStreamID - parses id returned by StreamID.
+// Plain number given by earlier versions has zero epoch, so client is reset
+func ParseStreamID(s string) (epoch int64, id uint64, err error) {
+	epochPart, idPart, ok := strings.Cut(s, "-")
+	if !ok {
+		epochPart, idPart = "0", s
+	}
+
+	epoch, err = strconv.ParseInt(epochPart, 10, 64)
+	if err != nil {
+		return 0, 0, ErrWrongEventID
+	}
+
+	id, err = strconv.ParseUint(idPart, 10, 64)
+	if err != nil {
+		return 0, 0, ErrWrongEventID
+	}
+
+	return epoch, id, nil
+}
+
+// visibleTo - checks if user receives change
+func (c Change) visibleTo(userID int) bool {
+	for _, id := range c.users {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriber - receiver of changes of one user
+type subscriber struct {
+	userID int
+	ch     chan Change
+}
+
+// Broker - delivers changes to subscribers and keeps bounded history for replay
+type Broker struct {
+	sync.Mutex
+	// epoch - start time of broker in nanoseconds, numbering of changes starts again with it
+	epoch       int64
+	lastID      uint64
+	history     []Change
+	size        int
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+// NewBroker - creates broker which keeps up to history last changes
+func NewBroker(history int) *Broker {
+	if history <= 0 {
+		history = DefaultHistory
+	}
+
+	return &Broker{
+		epoch:       time.Now().UnixNano(),
+		size:        history,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish - numbers change of event and sends it to owner and attendees, never blocks.
+// Subscriber which can not keep up is disconnected
+func (b *Broker) Publish(changeType string, event model.Event, users []int) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.lastID++
+	change := Change{
+		ID:      b.lastID,
+		Type:    changeType,
+		UserID:  event.UserID,
+		EventID: event.EventID,
+		Event:   event,
+		users:   users,
+		epoch:   b.epoch,
+	}
+
+	if len(b.history) == b.size {
+		copy(b.history, b.history[1:])
+		b.history = b.history[:b.size-1]
+	}
+	b.history = append(b.history, change)
+
+	for s := range b.subscribers {
+		if !change.visibleTo(s.userID) {
+			continue
+		}
+
+		select {
+		case s.ch <- change:
+		default:
+			b.drop(s)
+		}
+	}
+}
+
+// Epoch - returns start of broker, changes numbered by it have it in StreamID
+func (b *Broker) Epoch() int64 {
+	return b.epoch
+}
+
+// Subscribe - returns changes of user after lastID of epoch from history and channel with the next ones.
+// complete is false if some changes after lastID are not in history anymore or lastID is from another epoch.
+// Channel is closed on cancel, when broker is closed or when subscriber is too slow
+func (b *Broker) Subscribe(userID int, epoch int64, lastID uint64) (replay []Change, complete bool, changes <-chan Change, cancel func()) {
+	b.Lock()
+	defer b.Unlock()
+
+	complete = true
+	switch {
+	case epoch != b.epoch || lastID > b.lastID:
+		// numbering started again after restart, nothing is known about changes client missed
+		complete, lastID = false, b.lastID
+	case lastID < b.lastID:
+		// the oldest change in history must directly follow lastID
+		complete = len(b.history) > 0 && b.history[0].ID <= lastID+1
+	}
+
+	for _, change := range b.history {
+		if change.ID > lastID && change.visibleTo(userID) {
+			replay = append(replay, change)
+		}
+	}
+
+	s := &subscriber{userID: userID, ch: make(chan Change, subscriberBuffer)}
+	if b.closed {
+		close(s.ch)
+	} else {
+		b.subscribers[s] = struct{}{}
+	}
+
+	cancel = func() {
+		b.Lock()
+		b.drop(s)
+		b.Unlock()
+	}
+
+	return replay, complete, s.ch, cancel
+}
+
+// Close - disconnects all subscribers, used on server shutdown
+func (b *Broker) Close() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.closed = true
+	for s := range b.subscribers {
+		b.drop(s)
+	}
+}
+
+// drop - removes subscriber and closes its channel, must be called under lock
+func (b *Broker) drop(s *subscriber) {
+	if _, ok := b.subscribers[s]; !ok {
+		return
+	}
+	delete(b.subscribers, s)
+	close(s.ch)
+}
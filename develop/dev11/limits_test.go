@@ -0,0 +1,203 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"main.go/internal/rpc"
+	"main.go/internal/service"
+	"main.go/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	mux := http.NewServeMux()
+	handler.NewHandler().Register(mux)
+
+	// one request per second, two at once
+	limited := handler.RateLimit(handler.NewRateLimiter(1, 2), mux)
+
+	// get - sends request from client address
+	get := func(addr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "http://127.0.0.1:8080/events_for_day?user_id=1&date=2022-02-01", nil)
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+
+		limited.ServeHTTP(w, r)
+
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := get("10.0.0.1:5000"); w.Code != http.StatusOK {
+			t.Fatalf("request %d within burst\nShould: %d\nGot: %d", i+1, http.StatusOK, w.Code)
+		}
+	}
+
+	// another port of the same address is the same client
+	w := get("10.0.0.1:5001")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over burst\nShould: %d\nGot: %d", http.StatusTooManyRequests, w.Code)
+	}
+	if retry := w.Header().Get("Retry-After"); retry != "1" {
+		t.Errorf("wrong Retry-After\nShould: 1\nGot: %q", retry)
+	}
+
+	var response handler.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Err == "" {
+		t.Errorf("429 should have error in body\nGot: %s", w.Body)
+	}
+
+	if w := get("10.0.0.2:5000"); w.Code != http.StatusOK {
+		t.Errorf("other client is limited\nShould: %d\nGot: %d", http.StatusOK, w.Code)
+	}
+
+	// bucket is refilled with rate
+	time.Sleep(1100 * time.Millisecond)
+	if w := get("10.0.0.1:5000"); w.Code != http.StatusOK {
+		t.Errorf("token is not refilled\nShould: %d\nGot: %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestBodyLimitAndQuota(t *testing.T) {
+	mux := http.NewServeMux()
+	store := storage.NewEventStorage(storage.WithRules(service.Rules{MaxEvents: 2}))
+	handler.NewHandlerWithStore(store, handler.WithMaxBodyBytes(512)).Register(mux)
+
+	// serve - sends request through mux and returns recorder
+	serve := func(method, target, ifMatch string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	event := model.Event{UserID: 1, Title: "standup", Date: model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)}}
+
+	big := event
+	big.Descr = strings.Repeat("x", 1024)
+
+	batch := []byte(`{"operations": [{"op": "create", "event": ` + string(jsonEncode(big)) + `}]}`)
+	form := "user_id=1&title=standup&date=2022-02-01&descr=" + strings.Repeat("x", 1024)
+
+	tests := []struct {
+		name   string
+		target string
+		body   []byte
+		ctype  string
+	}{
+		{name: "json event", target: "/create_event", body: jsonEncode(big)},
+		{name: "form event", target: "/create_event", body: []byte(form), ctype: "application/x-www-form-urlencoded"},
+		{name: "batch", target: "/batch", body: batch},
+		{name: "answer", target: "/respond_event", body: jsonEncode(big)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "http://127.0.0.1:8080"+tt.target, bytes.NewReader(tt.body))
+			if tt.ctype != "" {
+				r.Header.Set("Content-Type", tt.ctype)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("body over limit\nShould: %d\nGot: %d %s", http.StatusRequestEntityTooLarge, w.Code, w.Body)
+			}
+		})
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := serve("POST", "/create_event", "", jsonEncode(event)); w.Code != http.StatusOK {
+			t.Fatalf("event within quota is not created\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body)
+		}
+	}
+
+	if w := serve("POST", "/create_event", "", jsonEncode(event)); w.Code != http.StatusInsufficientStorage {
+		t.Errorf("event over quota\nShould: %d\nGot: %d %s", http.StatusInsufficientStorage, w.Code, w.Body)
+	}
+
+	// quota is per user
+	other := event
+	other.UserID = 2
+	if w := serve("POST", "/create_event", "", jsonEncode(other)); w.Code != http.StatusOK {
+		t.Errorf("event of another user\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	w := serve("POST", "/batch", "", []byte(`{"operations": [{"op": "create", "event": `+string(jsonEncode(event))+`}]}`))
+	var response handler.BatchResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Results) != 1 || response.Results[0].Status != http.StatusInsufficientStorage {
+		t.Errorf("batch operation over quota\nShould: %d\nGot: %s", http.StatusInsufficientStorage, w.Body)
+	}
+
+	// deleted event frees quota, but can not be restored when quota is taken again
+	if w := serve("DELETE", "/users/1/events/1", `"1"`, nil); w.Code != http.StatusOK {
+		t.Fatalf("event is not deleted\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body)
+	}
+	if w := serve("POST", "/create_event", "", jsonEncode(event)); w.Code != http.StatusOK {
+		t.Fatalf("deleted event should free quota\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body)
+	}
+	if w := serve("POST", "/users/1/events/1/restore", "", nil); w.Code != http.StatusInsufficientStorage {
+		t.Errorf("restore over quota\nShould: %d\nGot: %d %s", http.StatusInsufficientStorage, w.Code, w.Body)
+	}
+
+	// trash keeps as many events as quota, the earliest deleted one is purged with its history
+	for _, target := range []string{"/users/1/events/2", "/users/1/events/3"} {
+		if w := serve("DELETE", target, `"1"`, nil); w.Code != http.StatusOK {
+			t.Fatalf("event is not deleted\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body)
+		}
+	}
+	var trash handler.ResultResponse
+	_ = json.Unmarshal(serve("GET", "/trash?user_id=1", "", nil).Body.Bytes(), &trash)
+	if len(trash.Result) != 2 || trash.Result[0].EventID != 2 || trash.Result[1].EventID != 3 {
+		t.Errorf("wrong trash over quota\nShould: events 2 and 3\nGot: %v", trash.Result)
+	}
+	if w := serve("GET", "/events/1/history?user_id=1", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("history of purged event\nShould: %d\nGot: %d %s", http.StatusNotFound, w.Code, w.Body)
+	}
+
+	// calendar over quota is not imported partly
+	vevent := "BEGIN:VEVENT\r\nDTSTART:20220201T100000Z\r\nSUMMARY:imported\r\nEND:VEVENT\r\n"
+	calendar := "BEGIN:VCALENDAR\r\n" + strings.Repeat(vevent, 3) + "END:VCALENDAR\r\n"
+	if w := serve("POST", "/import_ics?user_id=3", "", []byte(calendar)); w.Code != http.StatusInsufficientStorage {
+		t.Errorf("import over quota\nShould: %d\nGot: %d %s", http.StatusInsufficientStorage, w.Code, w.Body)
+	}
+	if w := serve("GET", "/calendar.ics?user_id=3", "", nil); strings.Contains(w.Body.String(), "imported") {
+		t.Errorf("import over quota should create nothing\nGot: %s", w.Body)
+	}
+}
+
+func TestJSONRPCBodyLimit(t *testing.T) {
+	server := rpc.NewServer(service.New(storage.NewEventStorage()), rpc.WithMaxBodyBytes(512))
+
+	// post - sends body to server and returns status
+	post := func(body string) int {
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/rpc", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		server.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	title := strings.Repeat("x", 1024)
+	if code := post(`{"jsonrpc": "2.0", "method": "createEvent", "params": {"event": {"user_id": 1, "title": "` + title + `", "date": "2022-02-01"}}, "id": 1}`); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("body over limit\nShould: %d\nGot: %d", http.StatusRequestEntityTooLarge, code)
+	}
+
+	if code := post(`{"jsonrpc": "2.0", "method": "trash", "params": {"user_id": 1}, "id": 1}`); code != http.StatusOK {
+		t.Errorf("body within limit\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+}
@@ -0,0 +1,66 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"main.go/internal/handler"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+
+	mux := http.NewServeMux()
+	handler.NewHandler().Register(mux)
+	server := handler.Logging(logger, mux)
+
+	// record - sends request and returns response with decoded log record
+	record := func(r *http.Request) (*httptest.ResponseRecorder, map[string]interface{}) {
+		out.Reset()
+		w := httptest.NewRecorder()
+
+		server.ServeHTTP(w, r)
+
+		var rec map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &rec); err != nil {
+			t.Fatalf("log record is not json: %v %s", err, out.String())
+		}
+		return w, rec
+	}
+
+	r := httptest.NewRequest("GET", "http://127.0.0.1:8080/events_for_day?user_id=5&date=2022-02-01", nil)
+	r.Header.Set("X-Request-ID", "abc-123")
+
+	w, rec := record(r)
+
+	if got := w.Header().Get("X-Request-ID"); got != "abc-123" {
+		t.Errorf("request id is not propagated\nShould: abc-123\nGot: %s", got)
+	}
+	if rec["request_id"] != "abc-123" || rec["status"] != float64(http.StatusOK) || rec["user_id"] != float64(5) {
+		t.Errorf("wrong log record\nShould: abc-123 200 5\nGot: %v", rec)
+	}
+	if rec["bytes"] != float64(w.Body.Len()) {
+		t.Errorf("wrong response size\nShould: %d\nGot: %v", w.Body.Len(), rec["bytes"])
+	}
+	if rec["remote_addr"] == "" || rec["method"] != "GET" {
+		t.Errorf("request is not described\nGot: %v", rec)
+	}
+
+	r = httptest.NewRequest("GET", "http://127.0.0.1:8080/create_event", nil)
+	w, rec = record(r)
+
+	id := w.Header().Get("X-Request-ID")
+	if id == "" || rec["request_id"] != id {
+		t.Errorf("request id should be generated\nShould: %s\nGot: %v", id, rec["request_id"])
+	}
+	if rec["status"] != float64(http.StatusMethodNotAllowed) {
+		t.Errorf("wrong status\nShould: %d\nGot: %v", http.StatusMethodNotAllowed, rec["status"])
+	}
+	if _, ok := rec["user_id"]; ok {
+		t.Errorf("request without user should not log user\nGot: %v", rec)
+	}
+}
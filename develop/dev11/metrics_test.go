@@ -0,0 +1,86 @@
+package dev11
+
+import (
+	"bytes"
+	"main.go/internal/auth"
+	"main.go/internal/handler"
+	"main.go/internal/metrics"
+	"main.go/internal/model"
+	"main.go/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.NewGaugeFunc("calendar_events", "Number of stored events.", func() float64 { return 3 })
+
+	mux := http.NewServeMux()
+	handler.NewHandler().Register(mux)
+	mux.Handle("GET /metrics", registry)
+	server := handler.Metrics(registry, mux)
+
+	// send - serves request through middleware
+	send := func(method, target string, body []byte) {
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		server.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	event := model.Event{UserID: 1, Date: model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)}}
+	send("POST", "/create_event", jsonEncode(event))
+	send("POST", "/create_event", []byte("{"))
+	send("GET", "/create_event", nil)
+	send("GET", "/events_for_day?user_id=1&date=2022-02-01", nil)
+	send("GET", "/missing", nil)
+
+	r := httptest.NewRequest("GET", "http://127.0.0.1:8080/metrics", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("wrong content type\nGot: %s", w.Header().Get("Content-Type"))
+	}
+
+	text := w.Body.String()
+	for _, line := range []string{
+		`# TYPE http_requests_total counter`,
+		`http_requests_total{route="POST /create_event",method="POST",status="200"} 1`,
+		`http_requests_total{route="POST /create_event",method="POST",status="400"} 1`,
+		`http_requests_total{route="/create_event",method="GET",status="405"} 1`,
+		`http_requests_total{route="GET /events_for_day",method="GET",status="200"} 1`,
+		`http_requests_total{route="unmatched",method="GET",status="404"} 1`,
+		`# TYPE http_request_duration_seconds histogram`,
+		`http_request_duration_seconds_bucket{route="POST /create_event",method="POST",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{route="GET /events_for_day",method="GET"} 1`,
+		`calendar_events 3`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("metrics have no line\nShould: %s\nGot:\n%s", line, text)
+		}
+	}
+}
+
+func TestMetricsAuthentication(t *testing.T) {
+	api := handler.NewHandlerWithStore(storage.NewEventStorage(), handler.WithAuth(auth.New("secret", map[string]int{"key-1": 1})))
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", api.Authenticate(metrics.NewRegistry()))
+
+	r := httptest.NewRequest("GET", "http://127.0.0.1:8080/metrics", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("metrics without credentials\nShould: %d\nGot: %d", http.StatusUnauthorized, w.Code)
+	}
+
+	r = httptest.NewRequest("GET", "http://127.0.0.1:8080/metrics", nil)
+	r.Header.Set("X-API-Key", "key-1")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("metrics with credentials\nShould: %d\nGot: %d", http.StatusOK, w.Code)
+	}
+}
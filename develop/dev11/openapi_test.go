@@ -0,0 +1,462 @@
+package dev11
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openAPI - parsed OpenAPI document which validates responses against its schemas
+type openAPI struct {
+	doc map[string]any
+}
+
+// lookup - returns node which $ref like #/components/schemas/Event points to
+func (s *openAPI) lookup(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("only local $ref is supported: %s", ref)
+	}
+
+	var node any = s.doc
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("wrong $ref %s", ref)
+		}
+		if node, ok = object[key]; !ok {
+			return nil, fmt.Errorf("wrong $ref %s", ref)
+		}
+	}
+
+	object, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("$ref %s is not an object", ref)
+	}
+	return object, nil
+}
+
+// resolve - follows $ref of node
+func (s *openAPI) resolve(node map[string]any) (map[string]any, error) {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node, nil
+		}
+
+		var err error
+		if node, err = s.lookup(ref); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// checkRefs - checks that every $ref of document points to existing node
+func (s *openAPI) checkRefs(node any) error {
+	switch v := node.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			if _, err := s.lookup(ref); err != nil {
+				return err
+			}
+		}
+		for _, child := range v {
+			if err := s.checkRefs(child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if err := s.checkRefs(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// operation - returns operation of path and method
+func (s *openAPI) operation(path, method string) (map[string]any, bool) {
+	paths, _ := s.doc["paths"].(map[string]any)
+	item, _ := paths[path].(map[string]any)
+	op, ok := item[strings.ToLower(method)].(map[string]any)
+	return op, ok
+}
+
+// response - returns documented response of operation for status, default one is used for undocumented status
+func (s *openAPI) response(op map[string]any, status int) (map[string]any, error) {
+	responses, _ := op["responses"].(map[string]any)
+
+	node, ok := responses[fmt.Sprint(status)].(map[string]any)
+	if !ok {
+		if node, ok = responses["default"].(map[string]any); !ok {
+			return nil, fmt.Errorf("status %d is not documented", status)
+		}
+	}
+
+	return s.resolve(node)
+}
+
+// validateResponse - checks content type and body of response against documented response
+func (s *openAPI) validateResponse(response map[string]any, w *httptest.ResponseRecorder) error {
+	content, _ := response["content"].(map[string]any)
+	if len(content) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	media, ok := content[mediaType].(map[string]any)
+	if !ok {
+		return fmt.Errorf("content type %q is not documented", w.Header().Get("Content-Type"))
+	}
+
+	if mediaType != "application/json" {
+		return nil
+	}
+
+	schema, _ := media["schema"].(map[string]any)
+
+	decoder := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	decoder.UseNumber()
+
+	var body any
+	if err := decoder.Decode(&body); err != nil {
+		return fmt.Errorf("body is not json: %v", err)
+	}
+
+	return s.validate(schema, body, "body")
+}
+
+// validate - checks value against schema, supports subset of OpenAPI schema used by the document
+func (s *openAPI) validate(schema map[string]any, value any, at string) error {
+	schema, err := s.resolve(schema)
+	if err != nil {
+		return err
+	}
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		matched := 0
+		for _, alternative := range oneOf {
+			if s.validate(alternative.(map[string]any), value, at) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: should match exactly one schema of oneOf, matches %d", at, matched)
+		}
+		return nil
+	}
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", at)
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", at, value, enum)
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: should be object, got %T", at, value)
+		}
+		return s.validateObject(schema, object, at)
+
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: should be array, got %T", at, value)
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range array {
+			if err := s.validate(items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: should be string, got %T", at, value)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				return fmt.Errorf("%s: %q is not date-time", at, str)
+			}
+		}
+
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: should be integer, got %T", at, value)
+		}
+		if _, err := number.Int64(); err != nil {
+			return fmt.Errorf("%s: %s is not integer", at, number)
+		}
+
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s: should be number, got %T", at, value)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: should be boolean, got %T", at, value)
+		}
+	}
+
+	return nil
+}
+
+// validateObject - checks required, documented and additional properties of object
+func (s *openAPI) validateObject(schema, object map[string]any, at string) error {
+	properties, _ := schema["properties"].(map[string]any)
+
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				return fmt.Errorf("%s: required property %q is missing", at, name)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if property, ok := properties[name].(map[string]any); ok {
+			if err := s.validate(property, object[name], at+"."+name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: property %q is not documented", at, name)
+			}
+		case map[string]any:
+			if err := s.validate(additional, object[name], at+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func TestOpenAPI(t *testing.T) {
+	api := handler.NewHandler()
+	mux := http.NewServeMux()
+	api.Register(mux)
+
+	r := httptest.NewRequest("GET", "http://127.0.0.1:8080/openapi.json", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	spec := &openAPI{}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec.doc); err != nil {
+		t.Fatalf("document is not json: %v", err)
+	}
+	if version, _ := spec.doc["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		t.Fatalf("wrong version of document\nShould: 3.x\nGot: %q", version)
+	}
+	if err := spec.checkRefs(spec.doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// every route is documented and nothing else is
+	routes := make(map[string]bool)
+	for _, pattern := range api.Routes() {
+		routes[pattern] = true
+
+		method, path, _ := strings.Cut(pattern, " ")
+		if _, ok := spec.operation(path, method); !ok {
+			t.Errorf("route is not documented: %s", pattern)
+		}
+	}
+
+	paths, _ := spec.doc["paths"].(map[string]any)
+	for path, item := range paths {
+		for method := range item.(map[string]any) {
+			if pattern := strings.ToUpper(method) + " " + path; !routes[pattern] {
+				t.Errorf("documented operation is not registered: %s", pattern)
+			}
+		}
+	}
+
+	// served - operations which answered with success
+	served := make(map[string]bool)
+
+	// do - sends request and validates response against document
+	do := func(method, target string, headers map[string]string, body []byte, status int) *httptest.ResponseRecorder {
+		t.Helper()
+
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		if strings.HasPrefix(target, "/events/stream") {
+			ctx, cancel := context.WithTimeout(r.Context(), 100*time.Millisecond)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		if w.Code != status {
+			t.Errorf("%s %s\nShould: %d\nGot: %d %s", method, target, status, w.Code, w.Body)
+		}
+
+		// fallback of path without method answers 405 for every operation
+		pattern := r.Pattern
+		if !strings.Contains(pattern, " ") {
+			pattern = method + " " + pattern
+		}
+		opMethod, path, _ := strings.Cut(pattern, " ")
+		if opMethod == http.MethodHead {
+			opMethod = http.MethodGet
+		}
+
+		op, ok := spec.operation(path, opMethod)
+		if !ok {
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Errorf("%s %s: operation %s is not documented", method, target, pattern)
+				return w
+			}
+			op = map[string]any{"responses": map[string]any{"default": map[string]any{"$ref": "#/components/responses/Error"}}}
+		}
+
+		response, err := spec.response(op, w.Code)
+		if err == nil {
+			err = spec.validateResponse(response, w)
+		}
+		if err != nil {
+			t.Errorf("%s %s answered %d not by document: %v\n%s", method, target, w.Code, err, w.Body)
+		}
+
+		if w.Code < 300 {
+			served[opMethod+" "+path] = true
+		}
+
+		return w
+	}
+
+	jsonType := map[string]string{"Content-Type": "application/json"}
+	formType := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	ifMatch := func(version int) map[string]string {
+		return map[string]string{"If-Match": fmt.Sprintf(`"%d"`, version)}
+	}
+
+	start := time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)
+	end := model.Date{Time: start.Add(time.Hour)}
+	event := model.Event{
+		UserID:    1,
+		Title:     "planning",
+		Descr:     "quarter goals",
+		Date:      model.Date{Time: start},
+		End:       &end,
+		Attendees: []model.Attendee{{UserID: 2}},
+		Reminders: []model.Reminder{{MinutesBefore: 10}},
+	}
+
+	do("GET", "/openapi.json", nil, nil, http.StatusOK)
+
+	do("POST", "/create_event", map[string]string{"Idempotency-Key": "planning"}, jsonEncode(event), http.StatusOK)
+	do("POST", "/create_event", formType,
+		[]byte("user_id=1&title=sync&date=2022-02-02T09:00&end=2022-02-02T09:30&rrule=FREQ%3DWEEKLY%3BCOUNT%3D3"), http.StatusOK)
+	do("POST", "/users/1/events", nil, jsonEncode(model.Event{UserID: 1, Title: "lunch", Date: model.Date{Time: start.Add(3 * time.Hour)}}), http.StatusOK)
+	do("POST", "/create_event", map[string]string{"Content-Type": "text/plain"}, []byte("title=x"), http.StatusUnsupportedMediaType)
+	do("POST", "/create_event", jsonType, []byte(`{"user_id": 1, "date": "tomorrow"}`), http.StatusBadRequest)
+
+	event.EventID = 1
+	event.Title = "planning of quarter"
+	event.Version = 1
+	do("POST", "/update_event", nil, jsonEncode(event), http.StatusOK)
+	do("POST", "/update_event", nil, jsonEncode(event), http.StatusConflict)
+	do("POST", "/update_event", ifMatch(1), jsonEncode(model.Event{UserID: 1, EventID: 1, Title: "stale", Date: event.Date}), http.StatusPreconditionFailed)
+	do("POST", "/update_event", nil, jsonEncode(model.Event{UserID: 1, EventID: 1, Title: "no version", Date: event.Date}), http.StatusPreconditionRequired)
+
+	do("GET", "/events_for_day?user_id=1&date=2022-02-01", nil, nil, http.StatusOK)
+	do("GET", "/events_for_week?user_id=1&date=2022-02-01&tz=Europe/Moscow", nil, nil, http.StatusOK)
+	do("GET", "/events_for_month?user_id=1&date=2022-02-01", nil, nil, http.StatusOK)
+	do("GET", "/events_for_day?user_id=0&date=2022-02-01", nil, nil, http.StatusBadRequest)
+	do("GET", "/conflicts?user_id=1&start=2022-02-01T10:30:00Z&end=2022-02-01T11:30:00Z", nil, nil, http.StatusOK)
+
+	ics := do("GET", "/calendar.ics?user_id=1", nil, nil, http.StatusOK)
+	do("POST", "/import_ics?user_id=3", map[string]string{"Content-Type": "text/calendar"}, ics.Body.Bytes(), http.StatusOK)
+	do("POST", "/import_ics?user_id=3", map[string]string{"Content-Type": "text/calendar"},
+		[]byte("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:yearly\r\nDTSTART:20220101T100000Z\r\nRRULE:FREQ=YEARLY;BYMONTH=1\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"),
+		http.StatusOK)
+
+	do("GET", "/events?user_id=1&from=2022-02-01&to=2022-03-01&limit=1", nil, nil, http.StatusOK)
+	do("GET", "/users/1/events?from=2022-02-01&to=2022-03-01", nil, nil, http.StatusOK)
+	do("GET", "/events/stream?user_id=1&last_event_id=1", nil, nil, http.StatusOK)
+
+	do("POST", "/respond_event", nil, []byte(`{"user_id": 2, "owner_id": 1, "event_id": 1, "status": "accepted"}`), http.StatusOK)
+	do("PUT", "/users/2/invitations/1/1", nil, []byte(`{"status": "tentative"}`), http.StatusOK)
+	do("PUT", "/users/2/invitations/1/9", nil, []byte(`{"status": "tentative"}`), http.StatusNotFound)
+	do("GET", "/invitations?user_id=2", nil, nil, http.StatusOK)
+	do("GET", "/users/2/invitations", nil, nil, http.StatusOK)
+
+	do("GET", "/search?user_id=1&q=plan", nil, nil, http.StatusOK)
+	do("GET", "/users/1/search?q=sync&from=2022-02-01&to=2022-03-01", nil, nil, http.StatusOK)
+	do("GET", "/search?user_id=1", nil, nil, http.StatusBadRequest)
+
+	batch := `{"operations": [{"op": "create", "event": {"user_id": 1, "title": "retro", "date": "2022-02-04T15:00:00Z"}},` +
+		`{"op": "update", "event": {"user_id": 1, "event_id": 3, "title": "late lunch", "date": "2022-02-01T14:00:00Z"}}]}`
+	do("POST", "/batch", nil, []byte(batch), http.StatusOK)
+	do("POST", "/batch", nil, []byte(`{"atomic": true, "operations": [{"op": "delete", "event": {"user_id": 1, "event_id": 42, "version": 1}}]}`), http.StatusNotFound)
+
+	do("GET", "/users/1/events/3", nil, nil, http.StatusOK)
+	do("PUT", "/users/1/events/3", ifMatch(1), jsonEncode(model.Event{Title: "long lunch", Date: model.Date{Time: start.Add(3 * time.Hour)}}), http.StatusOK)
+	do("DELETE", "/users/1/events/3", ifMatch(2), nil, http.StatusOK)
+	do("GET", "/users/1/events/3", nil, nil, http.StatusNotFound)
+	do("POST", "/delete_event", nil, []byte(`{"user_id": 1, "event_id": 2, "version": 1}`), http.StatusOK)
+
+	do("GET", "/events/1/history?user_id=1", nil, nil, http.StatusOK)
+	do("GET", "/users/1/events/2/history", nil, nil, http.StatusOK)
+	do("GET", "/trash?user_id=1", nil, nil, http.StatusOK)
+	do("GET", "/users/1/trash", nil, nil, http.StatusOK)
+	do("POST", "/events/2/restore?user_id=1", nil, nil, http.StatusOK)
+	do("POST", "/users/1/events/3/restore", nil, nil, http.StatusOK)
+
+	do("GET", "/freebusy?user_ids=1,2&from=2022-02-01&to=2022-02-02", nil, nil, http.StatusOK)
+	do("GET", "/suggest_slot?user_ids=1,2&duration=1h&from=2022-02-01&to=2022-02-08", nil, nil, http.StatusOK)
+	do("GET", "/suggest_slot?user_ids=1&duration=1h&from=2022-02-05&to=2022-02-07", nil, nil, http.StatusServiceUnavailable)
+
+	do("DELETE", "/events_for_day", nil, nil, http.StatusMethodNotAllowed)
+	do("HEAD", "/users/1/trash", nil, nil, http.StatusOK)
+
+	for _, pattern := range api.Routes() {
+		if !served[pattern] {
+			t.Errorf("no successful response of %s is validated", pattern)
+		}
+	}
+}
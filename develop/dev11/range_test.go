@@ -0,0 +1,203 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestEventsInRange(t *testing.T) {
+	api := handler.NewHandler()
+
+	base := time.Date(2022, 2, 1, 9, 0, 0, 0, time.UTC)
+
+	var events []model.Event
+	for i := 1; i <= 20; i++ {
+		// pairs of events start at the same time
+		events = append(events, model.Event{
+			EventID: i,
+			UserID:  1,
+			Title:   fmt.Sprintf("event %d", i),
+			Date:    model.Date{Time: base.Add(time.Duration((i-1)/2) * 24 * time.Hour)},
+		})
+	}
+
+	// long event started before window
+	end := model.Date{Time: base.Add(24 * time.Hour)}
+	events = append(events, model.Event{EventID: 21, UserID: 1, Date: model.Date{Time: base.Add(-48 * time.Hour)}, End: &end})
+
+	events = append(events, model.Event{
+		EventID:    22,
+		UserID:     1,
+		Date:       model.Date{Time: base.Add(-time.Hour)},
+		Recurrence: &model.Recurrence{Freq: model.Daily, Interval: 3},
+	})
+
+	for _, event := range events {
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBuffer(jsonEncode(event)))
+		w := httptest.NewRecorder()
+
+		api.CreateEvent(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("event is not created: %s", w.Body.String())
+		}
+	}
+
+	// page - requests events of first user in February 2022
+	page := func(limit int, cursor string) handler.ResultResponse {
+		query := url.Values{}
+		query.Set("user_id", "1")
+		query.Set("from", "2022-02-01")
+		query.Set("to", "2022-03-01")
+		query.Set("limit", fmt.Sprint(limit))
+		query.Set("cursor", cursor)
+
+		r := httptest.NewRequest("GET", "http://127.0.0.1:8080/events?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+
+		var responseEvent handler.ResultResponse
+
+		api.GetEventsInRange(w, r)
+
+		err := json.Unmarshal(w.Body.Bytes(), &responseEvent)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("cannot get events: %s", w.Body.String())
+		}
+
+		return responseEvent
+	}
+
+	all := page(1000, "")
+	if all.NextCursor != "" {
+		t.Errorf("cursor on the last page: %s", all.NextCursor)
+	}
+
+	// 20 single events, event 21 lasting into window and 10 occurrences of event 22
+	if len(all.Result) != 31 {
+		t.Fatalf("wrong number of events\nShould: 31\nGot: %d", len(all.Result))
+	}
+
+	t.Run("chronological order", func(t *testing.T) {
+		for i := 1; i < len(all.Result); i++ {
+			prev, curr := all.Result[i-1], all.Result[i]
+			if curr.Date.Before(prev.Date.Time) || (curr.Date.Equal(prev.Date.Time) && curr.EventID <= prev.EventID) {
+				t.Errorf("events are not sorted: %v before %v", prev, curr)
+			}
+		}
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		var (
+			paged  []model.Event
+			cursor string
+		)
+
+		for pages := 0; ; pages++ {
+			if pages > len(all.Result) {
+				t.Fatal("pagination does not stop")
+			}
+
+			response := page(7, cursor)
+			paged = append(paged, response.Result...)
+
+			if response.NextCursor == "" {
+				break
+			}
+			cursor = response.NextCursor
+		}
+
+		if len(paged) != len(all.Result) {
+			t.Fatalf("wrong number of paged events\nShould: %d\nGot: %d", len(all.Result), len(paged))
+		}
+		for i := range paged {
+			if paged[i].EventID != all.Result[i].EventID || !paged[i].Date.Equal(all.Result[i].Date.Time) {
+				t.Errorf("wrong event on position %d\nShould: %v\nGot: %v", i, all.Result[i], paged[i])
+			}
+		}
+	})
+
+	t.Run("index follows updates", func(t *testing.T) {
+		moved := events[0]
+		moved.Date.Time = time.Date(2022, 4, 1, 9, 0, 0, 0, time.UTC)
+		moved.Version = 1
+
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/update_event", bytes.NewBuffer(jsonEncode(moved)))
+		api.UpdateEvent(httptest.NewRecorder(), r)
+
+		r = httptest.NewRequest("POST", "http://127.0.0.1:8080/delete_event", bytes.NewBuffer(jsonEncode(events[1])))
+		r.Header.Set("If-Match", `"1"`)
+		api.DeleteEvent(httptest.NewRecorder(), r)
+
+		if got := page(1000, ""); len(got.Result) != len(all.Result)-2 {
+			t.Errorf("wrong number of events after update\nShould: %d\nGot: %d", len(all.Result)-2, len(got.Result))
+		}
+	})
+
+	t.Run("invitation with the same id", func(t *testing.T) {
+		// event of another owner has the same start and id as event 3 of the first user
+		invitation := model.Event{
+			EventID:   3,
+			UserID:    2,
+			Title:     "invitation",
+			Date:      events[2].Date,
+			Attendees: []model.Attendee{{UserID: 1}},
+		}
+
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBuffer(jsonEncode(invitation)))
+		w := httptest.NewRecorder()
+
+		api.CreateEvent(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("event is not created: %s", w.Body.String())
+		}
+
+		unpaged := page(1000, "")
+
+		var (
+			paged  []model.Event
+			cursor string
+		)
+		for pages := 0; ; pages++ {
+			if pages > len(unpaged.Result) {
+				t.Fatal("pagination does not stop")
+			}
+
+			response := page(1, cursor)
+			paged = append(paged, response.Result...)
+
+			if response.NextCursor == "" {
+				break
+			}
+			cursor = response.NextCursor
+		}
+
+		if len(paged) != len(unpaged.Result) {
+			t.Fatalf("wrong number of paged events\nShould: %d\nGot: %d", len(unpaged.Result), len(paged))
+		}
+		for i := range paged {
+			if paged[i].EventID != unpaged.Result[i].EventID || paged[i].UserID != unpaged.Result[i].UserID {
+				t.Errorf("wrong event on position %d\nShould: %v\nGot: %v", i, unpaged.Result[i], paged[i])
+			}
+		}
+	})
+
+	t.Run("wrong cursor", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "http://127.0.0.1:8080/events?user_id=1&from=2022-02-01&to=2022-03-01&cursor=xyz", nil)
+		w := httptest.NewRecorder()
+
+		api.GetEventsInRange(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("wrong status\nShould: %d\nGot: %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
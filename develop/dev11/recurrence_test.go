@@ -0,0 +1,180 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOccurrences(t *testing.T) {
+	// date - returns time in UTC
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02T15:04", s)
+		if err != nil {
+			t.Fatalf("wrong test date %s: %v", s, err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name  string
+		event model.Event
+		from  string
+		to    string
+		want  []string
+	}{
+		{
+			name:  "single event",
+			event: model.Event{Date: model.Date{Time: date("2022-02-01T10:00")}},
+			from:  "2022-02-01T00:00", to: "2022-02-02T00:00",
+			want: []string{"2022-02-01T10:00"},
+		},
+		{
+			name: "daily with interval and count",
+			event: model.Event{
+				Date:       model.Date{Time: date("2022-02-01T10:00")},
+				Recurrence: &model.Recurrence{Freq: model.Daily, Interval: 2, Count: 3},
+			},
+			from: "2022-01-01T00:00", to: "2022-03-01T00:00",
+			want: []string{"2022-02-01T10:00", "2022-02-03T10:00", "2022-02-05T10:00"},
+		},
+		{
+			name: "weekly by day with exception",
+			event: model.Event{
+				Date:       model.Date{Time: date("2022-02-01T09:30")},
+				Recurrence: &model.Recurrence{Freq: model.Weekly, ByDay: []string{"TU", "TH"}},
+				Exceptions: []model.Date{{Time: date("2022-02-08T00:00")}},
+			},
+			from: "2022-02-07T00:00", to: "2022-02-14T00:00",
+			want: []string{"2022-02-10T09:30"},
+		},
+		{
+			name: "weekly until",
+			event: model.Event{
+				Date:       model.Date{Time: date("2022-02-01T09:30")},
+				Recurrence: &model.Recurrence{Freq: model.Weekly, Until: &model.Date{Time: date("2022-02-15T09:30")}},
+			},
+			from: "2022-01-01T00:00", to: "2023-01-01T00:00",
+			want: []string{"2022-02-01T09:30", "2022-02-08T09:30", "2022-02-15T09:30"},
+		},
+		{
+			name: "monthly skips short months",
+			event: model.Event{
+				Date:       model.Date{Time: date("2022-01-31T12:00")},
+				Recurrence: &model.Recurrence{Freq: model.Monthly, Count: 3},
+			},
+			from: "2022-01-01T00:00", to: "2023-01-01T00:00",
+			want: []string{"2022-01-31T12:00", "2022-03-31T12:00", "2022-05-31T12:00"},
+		},
+		{
+			name: "monthly last friday",
+			event: model.Event{
+				Date:       model.Date{Time: date("2022-01-28T18:00")},
+				Recurrence: &model.Recurrence{Freq: model.Monthly, ByDay: []string{"-1FR"}},
+			},
+			from: "2022-02-01T00:00", to: "2022-04-01T00:00",
+			want: []string{"2022-02-25T18:00", "2022-03-25T18:00"},
+		},
+		{
+			name: "yearly far from start",
+			event: model.Event{
+				Date:       model.Date{Time: date("2000-02-29T08:00")},
+				Recurrence: &model.Recurrence{Freq: model.Yearly},
+			},
+			from: "2021-01-01T00:00", to: "2025-01-01T00:00",
+			want: []string{"2024-02-29T08:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.event.Occurrences(date(tt.from), date(tt.to))
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("wrong occurrences\nShould: %v\nGot: %v", tt.want, got)
+			}
+			for i := range got {
+				if !got[i].Equal(date(tt.want[i])) {
+					t.Errorf("wrong occurrences\nShould: %v\nGot: %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRecurringEvents(t *testing.T) {
+	api := handler.NewHandler()
+
+	standup := model.Event{
+		EventID:    1,
+		UserID:     1,
+		Title:      "stand-up",
+		Date:       model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)},
+		Recurrence: &model.Recurrence{Freq: model.Weekly, ByDay: []string{"MO", "TU", "WE", "TH", "FR"}},
+	}
+
+	r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBuffer(jsonEncode(standup)))
+	w := httptest.NewRecorder()
+
+	api.CreateEvent(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("event is not created: %s", w.Body.String())
+	}
+
+	t.Run("occurrences in week", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "http://127.0.0.1:8080/events_for_week?user_id=1&date=2022-03-09", nil)
+		w := httptest.NewRecorder()
+
+		var responseEvent handler.ResultResponse
+
+		api.GetEventsForWeek(w, r)
+
+		err := json.Unmarshal(w.Body.Bytes(), &responseEvent)
+		if err != nil {
+			t.Fatalf("cannot read response: %v", err)
+		}
+
+		if len(responseEvent.Result) != 5 {
+			t.Fatalf("wrong number of occurrences\nShould: 5\nGot: %v", responseEvent.Result)
+		}
+		for i, event := range responseEvent.Result {
+			want := fmt.Sprintf("2022-03-%02dT10:00", 7+i)
+			if event.Date.Format("2006-01-02T15:04") != want || event.SeriesDate == nil {
+				t.Errorf("wrong occurrence\nShould: %s\nGot: %v", want, event)
+			}
+		}
+	})
+
+	t.Run("wrong rule", func(t *testing.T) {
+		rules := []*model.Recurrence{
+			{Freq: model.Weekly, ByDay: []string{"1MO"}},
+			{Freq: model.Monthly, ByDay: []string{"6MO"}},
+			{Freq: model.Monthly, ByDay: []string{"-53FR"}},
+			{Freq: model.Yearly, ByDay: []string{"54MO"}},
+			{Freq: model.Weekly, ByDay: []string{"MO", "TU", "MO"}},
+			{Freq: model.Monthly, ByDay: []string{"-1FR", "-1fr"}},
+		}
+
+		for _, rule := range rules {
+			event := standup
+			event.EventID = 2
+			event.Recurrence = rule
+
+			r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBuffer(jsonEncode(event)))
+			w := httptest.NewRecorder()
+
+			api.CreateEvent(w, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("wrong status for %v\nShould: %d\nGot: %d", rule.ByDay, http.StatusBadRequest, w.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,244 @@
+package dev11
+
+import (
+	"encoding/json"
+	"main.go/internal/model"
+	"main.go/internal/reminder"
+	"main.go/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReminders(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []reminder.Notification
+		failing  bool
+	)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		var n reminder.Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil || r.Header.Get("Idempotency-Key") != n.Key {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, n)
+	}))
+	defer receiver.Close()
+
+	// keys - returns keys of received notifications
+	keys := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var result []string
+		for _, n := range received {
+			result = append(result, n.Key)
+		}
+		return result
+	}
+
+	setFailing := func(value bool) {
+		mu.Lock()
+		failing = value
+		mu.Unlock()
+	}
+
+	store := storage.NewEventStorage()
+
+	meeting := model.Event{
+		UserID:    1,
+		EventID:   1,
+		Date:      model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)},
+		Reminders: []model.Reminder{{MinutesBefore: 15}, {MinutesBefore: 0}},
+	}
+	standup := model.Event{
+		UserID:     2,
+		EventID:    1,
+		Date:       model.Date{Time: time.Date(2022, 2, 1, 12, 0, 0, 0, time.UTC)},
+		Recurrence: &model.Recurrence{Freq: model.Daily},
+		Reminders:  []model.Reminder{{MinutesBefore: 5}},
+	}
+	for _, event := range []model.Event{meeting, standup} {
+		event := event
+		if err := store.CreateEvent(&event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stateFile := filepath.Join(t.TempDir(), "reminders.json")
+	now := time.Date(2022, 2, 1, 9, 0, 0, 0, time.UTC)
+
+	// open - creates scheduler as after restart
+	open := func() *reminder.Scheduler {
+		s, err := reminder.New(store, receiver.URL, stateFile,
+			reminder.WithClock(func() time.Time { return now }),
+			reminder.WithRetries(time.Second, time.Minute, 3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	// tick - moves clock and runs scheduler once
+	tick := func(s *reminder.Scheduler, at time.Time) {
+		now = at
+		if err := s.Tick(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scheduler := open()
+
+	tick(scheduler, time.Date(2022, 2, 1, 9, 46, 0, 0, time.UTC))
+	if got := keys(); len(got) != 1 || got[0] != "1-1-1643709600-15" {
+		t.Fatalf("reminder 15 minutes before is not sent\nShould: [1-1-1643709600-15]\nGot: %v", got)
+	}
+
+	setFailing(true)
+	tick(scheduler, time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC))
+	if scheduler.Pending() != 1 {
+		t.Fatalf("failed reminder should wait for retry\nShould: 1\nGot: %d", scheduler.Pending())
+	}
+
+	// restart keeps queue
+	if err := scheduler.Close(); err != nil {
+		t.Fatal(err)
+	}
+	scheduler = open()
+	setFailing(false)
+
+	tick(scheduler, time.Date(2022, 2, 1, 10, 0, 0, 500, time.UTC))
+	if len(keys()) != 1 {
+		t.Errorf("reminder should wait for backoff\nShould: 1 delivery\nGot: %v", keys())
+	}
+
+	tick(scheduler, time.Date(2022, 2, 1, 10, 0, 2, 0, time.UTC))
+	if got := keys(); len(got) != 2 || got[1] != "1-1-1643709600-0" {
+		t.Errorf("reminder is not retried\nShould: 1-1-1643709600-0\nGot: %v", got)
+	}
+
+	// restart does not fire sent reminders again
+	if err := scheduler.Close(); err != nil {
+		t.Fatal(err)
+	}
+	scheduler = open()
+
+	tick(scheduler, time.Date(2022, 2, 1, 10, 0, 3, 0, time.UTC))
+	if len(keys()) != 2 {
+		t.Errorf("reminders are repeated after restart\nShould: 2 deliveries\nGot: %v", keys())
+	}
+
+	// reminders of downtime are sent late, but sent
+	tick(scheduler, time.Date(2022, 2, 3, 13, 0, 0, 0, time.UTC))
+	if got := keys(); len(got) != 5 || got[2] != "2-1-1643716800-5" || got[4] != "2-1-1643889600-5" {
+		t.Errorf("reminders of recurring event are lost\nShould: 3 more deliveries\nGot: %v", got)
+	}
+
+	// the last attempt drops reminder
+	setFailing(true)
+	tick(scheduler, time.Date(2022, 2, 3, 11, 55, 0, 0, time.UTC).AddDate(0, 0, 1))
+	for i := 0; i < 3; i++ {
+		tick(scheduler, now.Add(time.Minute))
+	}
+	if scheduler.Pending() != 0 {
+		t.Errorf("reminder should be dropped after the last attempt\nShould: 0\nGot: %d", scheduler.Pending())
+	}
+
+	if err := scheduler.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReminderSlowWebhook(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	called := make(chan struct{})
+	release := make(chan struct{})
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		if calls == 1 {
+			close(called)
+		}
+		mu.Unlock()
+
+		<-release
+	}))
+	defer receiver.Close()
+
+	store := storage.NewEventStorage()
+	event := model.Event{
+		UserID:    1,
+		Date:      model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)},
+		Reminders: []model.Reminder{{MinutesBefore: 15}},
+	}
+	if err := store.CreateEvent(&event); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2022, 2, 1, 9, 40, 0, 0, time.UTC)
+	s, err := reminder.New(store, receiver.URL, filepath.Join(t.TempDir(), "reminders.json"),
+		reminder.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = time.Date(2022, 2, 1, 9, 46, 0, 0, time.UTC)
+
+	ticked := make(chan error)
+	go func() {
+		ticked <- s.Tick()
+	}()
+	<-called
+
+	// scheduler is not locked while webhook answers
+	pending := make(chan int)
+	go func() {
+		pending <- s.Pending()
+	}()
+	select {
+	case n := <-pending:
+		if n != 1 {
+			t.Errorf("reminder being sent should stay in queue\nShould: 1\nGot: %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("scheduler is locked while webhook is called")
+	}
+
+	// the next tick does not send reminder which is being sent
+	if err := s.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if calls != 1 {
+		t.Errorf("reminder being sent is sent again\nShould: 1 call\nGot: %d", calls)
+	}
+	mu.Unlock()
+
+	close(release)
+	if err := <-ticked; err != nil {
+		t.Fatal(err)
+	}
+	if s.Pending() != 0 {
+		t.Errorf("sent reminder should leave queue\nShould: 0\nGot: %d", s.Pending())
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,113 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMethodRouting(t *testing.T) {
+	mux := http.NewServeMux()
+	handler.NewHandler().Register(mux)
+
+	cases := []struct {
+		method, target string
+		want           int
+		allow          string
+	}{
+		{method: "GET", target: "/create_event", want: http.StatusMethodNotAllowed, allow: "POST"},
+		{method: "POST", target: "/events_for_day?user_id=1&date=2022-02-01", want: http.StatusMethodNotAllowed, allow: "GET, HEAD"},
+		{method: "PATCH", target: "/users/1/events/1", want: http.StatusMethodNotAllowed, allow: "GET, HEAD, PUT, DELETE"},
+		{method: "GET", target: "/events_for_day?user_id=1&date=2022-02-01", want: http.StatusOK},
+		{method: "HEAD", target: "/events_for_day?user_id=1&date=2022-02-01", want: http.StatusOK},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, "http://127.0.0.1:8080"+c.target, nil)
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		if w.Code != c.want {
+			t.Errorf("wrong status of %s %s\nShould: %d\nGot: %d %s", c.method, c.target, c.want, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Allow"); got != c.allow {
+			t.Errorf("wrong Allow header of %s %s\nShould: %q\nGot: %q", c.method, c.target, c.allow, got)
+		}
+	}
+}
+
+func TestRESTResources(t *testing.T) {
+	mux := http.NewServeMux()
+	handler.NewHandler().Register(mux)
+
+	// do - sends request through mux and decodes response
+	do := func(method, target, ifMatch string, body []byte) (int, handler.ResultResponse) {
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		var response handler.ResultResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	event := model.Event{Title: "meeting", Date: model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)}}
+
+	code, response := do("POST", "/users/7/events", "", jsonEncode(event))
+	if code != http.StatusOK || len(response.Result) != 1 {
+		t.Fatalf("event is not created\nShould: %d\nGot: %d %v", http.StatusOK, code, response)
+	}
+	if created := response.Result[0]; created.UserID != 7 || created.EventID != 1 {
+		t.Fatalf("event should get user from path\nShould: 7/1\nGot: %d/%d", created.UserID, created.EventID)
+	}
+
+	code, response = do("GET", "/users/7/events/1", "", nil)
+	if code != http.StatusOK || len(response.Result) != 1 || response.Result[0].Title != "meeting" {
+		t.Errorf("wrong event\nShould: %v\nGot: %d %v", event, code, response)
+	}
+
+	code, response = do("GET", "/users/7/events?from=2022-02-01&to=2022-02-02", "", nil)
+	if code != http.StatusOK || len(response.Result) != 1 {
+		t.Errorf("wrong events in range\nShould: 1 event\nGot: %d %v", code, response)
+	}
+
+	event.Title = "moved meeting"
+	code, _ = do("PUT", "/users/7/events/1", `"1"`, jsonEncode(event))
+	if code != http.StatusOK {
+		t.Errorf("event is not updated\nShould: %d\nGot: %d", http.StatusOK, code)
+	}
+
+	event.UserID = 8
+	code, _ = do("PUT", "/users/7/events/1", "", jsonEncode(event))
+	if code != http.StatusBadRequest {
+		t.Errorf("user in body should match path\nShould: %d\nGot: %d", http.StatusBadRequest, code)
+	}
+
+	code, response = do("DELETE", "/users/7/events/1", `"2"`, nil)
+	if code != http.StatusOK || len(response.Result) != 1 || response.Result[0].Title != "moved meeting" {
+		t.Errorf("event is not deleted\nShould: %d\nGot: %d %v", http.StatusOK, code, response)
+	}
+
+	for _, method := range []string{"GET", "DELETE"} {
+		code, _ = do(method, "/users/7/events/1", `"2"`, nil)
+		if code != http.StatusNotFound {
+			t.Errorf("%s of missing event\nShould: %d\nGot: %d", method, http.StatusNotFound, code)
+		}
+	}
+
+	event.UserID = 0
+	code, _ = do("PUT", "/users/7/events/1", `"2"`, jsonEncode(event))
+	if code != http.StatusNotFound {
+		t.Errorf("update of missing event\nShould: %d\nGot: %d", http.StatusNotFound, code)
+	}
+}
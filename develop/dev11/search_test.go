@@ -0,0 +1,126 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	mux := http.NewServeMux()
+	handler.NewHandler().Register(mux)
+
+	// do - sends request through mux and decodes response
+	do := func(method, target, ifMatch string, body []byte) (int, handler.ResultResponse) {
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		var response handler.ResultResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	// search - returns titles of found events
+	search := func(q, extra string) []string {
+		code, response := do("GET", "/search?user_id=1&q="+url.QueryEscape(q)+extra, "", nil)
+		if code != http.StatusOK {
+			t.Fatalf("search failed\nShould: %d\nGot: %d", http.StatusOK, code)
+		}
+
+		var titles []string
+		for _, event := range response.Result {
+			titles = append(titles, event.Title)
+		}
+		return titles
+	}
+
+	day := func(d int) model.Date {
+		return model.Date{Time: time.Date(2022, 2, d, 10, 0, 0, 0, time.UTC)}
+	}
+
+	events := []model.Event{
+		{UserID: 1, Title: "Budget review", Descr: "Quarterly budget of marketing", Date: day(1)},
+		{UserID: 1, Title: "Team sync", Descr: "Weekly", Date: day(2), Recurrence: &model.Recurrence{Freq: model.Weekly, Count: 4}},
+		{UserID: 1, Title: "Budgeting workshop", Date: day(10)},
+		{UserID: 2, Title: "Budget of sales", Date: day(3)},
+		{UserID: 3, Title: "Budget planning", Date: day(4), Attendees: []model.Attendee{{UserID: 1}}},
+	}
+	for _, event := range events {
+		if code, _ := do("POST", "/create_event", "", jsonEncode(event)); code != http.StatusOK {
+			t.Fatalf("event is not created\nShould: %d\nGot: %d", http.StatusOK, code)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		q     string
+		extra string
+		want  []string
+	}{
+		{name: "prefix", q: "budg", want: []string{"Budget review", "Budget planning", "Budgeting workshop"}},
+		{name: "case and punctuation", q: "BUDGET,", want: []string{"Budget review", "Budget planning", "Budgeting workshop"}},
+		{name: "all terms", q: "budget market", want: []string{"Budget review"}},
+		{name: "description", q: "weekly", want: []string{"Team sync"}},
+		{name: "no match", q: "holiday", want: nil},
+		{name: "date range", q: "budget", extra: "&from=2022-02-02&to=2022-02-09", want: []string{"Budget planning"}},
+		{name: "occurrences in range", q: "sync", extra: "&from=2022-02-05&to=2022-02-20", want: []string{"Team sync", "Team sync"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := search(tc.q, tc.extra); strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("wrong events\nShould: %v\nGot: %v", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("index follows changes", func(t *testing.T) {
+		moved := events[0]
+		moved.EventID = 1
+		moved.Title = "Forecast review"
+		moved.Descr = ""
+		if code, _ := do("PUT", "/users/1/events/1", `"1"`, jsonEncode(moved)); code != http.StatusOK {
+			t.Fatalf("event is not updated\nShould: %d\nGot: %d", http.StatusOK, code)
+		}
+		if code, _ := do("DELETE", "/users/1/events/3", `"1"`, nil); code != http.StatusOK {
+			t.Fatalf("event is not deleted\nShould: %d\nGot: %d", http.StatusOK, code)
+		}
+
+		want := []string{"Budget planning"}
+		if got := search("budget", ""); strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("wrong events\nShould: %v\nGot: %v", want, got)
+		}
+
+		want = []string{"Forecast review"}
+		if got := search("forecast", ""); strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("wrong events\nShould: %v\nGot: %v", want, got)
+		}
+	})
+
+	t.Run("declined invitation", func(t *testing.T) {
+		if code, _ := do("PUT", "/users/1/invitations/3/1", "", []byte(`{"status": "declined"}`)); code != http.StatusOK {
+			t.Fatalf("answer is not saved\nShould: %d\nGot: %d", http.StatusOK, code)
+		}
+		if got := search("planning", ""); len(got) != 0 {
+			t.Errorf("declined event should not be found\nShould: []\nGot: %v", got)
+		}
+	})
+
+	for _, target := range []string{"/search?user_id=1", "/search?user_id=1&q=%21%21", "/search?user_id=1&q=a&from=2022-02-01"} {
+		if code, _ := do("GET", target, "", nil); code != http.StatusBadRequest {
+			t.Errorf("wrong request %s\nShould: %d\nGot: %d", target, http.StatusBadRequest, code)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package dev11
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"main.go/internal/handler"
+	"main.go/internal/metrics"
+	"main.go/internal/model"
+	"main.go/internal/stream"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseMessage - message read from event stream
+type sseMessage struct {
+	id, event string
+	change    stream.Change
+}
+
+// readMessages - reads n messages from event stream, heartbeats are skipped
+func readMessages(t *testing.T, r *bufio.Reader, n int) []sseMessage {
+	t.Helper()
+
+	var (
+		messages []sseMessage
+		current  sseMessage
+	)
+
+	for len(messages) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream is closed: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+
+		switch {
+		case line == "":
+			if current.event != "" {
+				messages = append(messages, current)
+			}
+			current = sseMessage{}
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.change)
+		}
+	}
+
+	return messages
+}
+
+func TestEventStream(t *testing.T) {
+	mux := http.NewServeMux()
+	handler.NewHandler().Register(mux)
+
+	// stream goes through middlewares which wrap ResponseWriter
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := httptest.NewServer(handler.Logging(logger, handler.Metrics(metrics.NewRegistry(), mux)))
+	defer server.Close()
+
+	// connect - opens stream of user
+	connect := func(userID, lastEventID string) (*http.Response, *bufio.Reader) {
+		req, _ := http.NewRequest("GET", server.URL+"/events/stream?user_id="+userID, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("stream is not opened\nShould: %d text/event-stream\nGot: %d %s",
+				http.StatusOK, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	// post - sends event to endpoint
+	post := func(path string, event model.Event) {
+		resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(jsonEncode(event)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s failed: %d", path, resp.StatusCode)
+		}
+	}
+
+	owner, ownerStream := connect("1", "")
+	guest, guestStream := connect("2", "")
+	defer guest.Body.Close()
+
+	event := model.Event{
+		UserID:    1,
+		EventID:   1,
+		Title:     "review",
+		Date:      model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)},
+		Attendees: []model.Attendee{{UserID: 2}},
+	}
+	post("/create_event", event)
+	post("/create_event", model.Event{UserID: 3, Date: event.Date})
+
+	event.Title = "moved review"
+	event.Version = 1
+	post("/update_event", event)
+	event.Version = 2
+	post("/delete_event", event)
+
+	// firstID - id of the first change received by owner
+	var firstID string
+
+	want := []string{stream.Create, stream.Update, stream.Delete}
+	for name, r := range map[string]*bufio.Reader{"owner": ownerStream, "attendee": guestStream} {
+		messages := readMessages(t, r, 3)
+		if name == "owner" {
+			firstID = messages[0].id
+		}
+		for i, message := range messages {
+			if message.event != want[i] || message.change.EventID != 1 || message.change.UserID != 1 {
+				t.Errorf("%s: wrong change %d\nShould: %s of event 1\nGot: %+v", name, i, want[i], message)
+			}
+		}
+		if messages[1].change.Event.Title != "moved review" {
+			t.Errorf("%s: update should carry new event\nShould: moved review\nGot: %s", name, messages[1].change.Event.Title)
+		}
+	}
+	owner.Body.Close()
+
+	// reconnect after the first change gets the rest
+	epoch, _, _ := strings.Cut(firstID, "-")
+	resumed, resumedStream := connect("1", firstID)
+	messages := readMessages(t, resumedStream, 2)
+	if messages[0].event != stream.Update || messages[1].event != stream.Delete || messages[1].id != epoch+"-4" {
+		t.Errorf("missed changes are not replayed\nShould: update, delete %s-4\nGot: %+v", epoch, messages)
+	}
+	resumed.Body.Close()
+
+	// client from before restart has to reload, even if numbering has reached its id again
+	for _, lastEventID := range []string{"1", "1-1", epoch + "-100"} {
+		reset, resetStream := connect("1", lastEventID)
+		if messages = readMessages(t, resetStream, 1); messages[0].event != "reset" {
+			t.Errorf("unknown Last-Event-ID %s should reset client\nShould: reset\nGot: %+v", lastEventID, messages)
+		}
+		reset.Body.Close()
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/events/stream?user_id=1", nil)
+	req.Header.Set("Last-Event-ID", "1-x")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("wrong Last-Event-ID\nShould: %d\nGot: %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestStreamHistory(t *testing.T) {
+	broker := stream.NewBroker(2)
+
+	for i := 1; i <= 3; i++ {
+		broker.Publish(stream.Create, model.Event{UserID: 1, EventID: i}, []int{1})
+	}
+
+	replay, complete, _, cancel := broker.Subscribe(1, broker.Epoch(), 1)
+	defer cancel()
+	if !complete || len(replay) != 2 {
+		t.Errorf("history should cover changes after 1\nShould: true 2\nGot: %v %d", complete, len(replay))
+	}
+
+	replay, complete, _, cancel = broker.Subscribe(1, broker.Epoch(), 0)
+	defer cancel()
+	if complete || len(replay) != 2 {
+		t.Errorf("the first change is out of history\nShould: false 2\nGot: %v %d", complete, len(replay))
+	}
+
+	_, _, changes, _ := broker.Subscribe(2, broker.Epoch(), 3)
+	broker.Close()
+	if _, ok := <-changes; !ok {
+		return
+	}
+	t.Errorf("channel should be closed with broker")
+}
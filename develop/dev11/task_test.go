@@ -7,10 +7,13 @@ import (
 	"io/ioutil"
 	"main.go/internal/handler"
 	"main.go/internal/model"
+	"main.go/internal/storage"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"sort"
 	"testing"
+	"time"
 )
 
 func TestService(t *testing.T) {
@@ -50,6 +53,7 @@ func TestService(t *testing.T) {
 			body := bytes.NewBuffer(jsonData)
 
 			r := httptest.NewRequest("POST", "http://127.0.0.1:8080/update_event", body)
+			r.Header.Set("If-Match", `"1"`)
 			w := httptest.NewRecorder()
 
 			var responseEvent handler.ResultResponse
@@ -178,6 +182,7 @@ func TestService(t *testing.T) {
 			body := bytes.NewBuffer(jsonData)
 
 			r := httptest.NewRequest("POST", "http://127.0.0.1:8080/delete_event", body)
+			r.Header.Set("If-Match", `"2"`)
 			w := httptest.NewRecorder()
 
 			var responseEvent handler.ResultResponse
@@ -261,6 +266,160 @@ func TestServiceWithBadData(t *testing.T) {
 
 }
 
+func TestEventKeys(t *testing.T) {
+	api := handler.NewHandler()
+
+	// keys of these events were equal when they were built as "%d%d"
+	events := []model.Event{
+		{EventID: 12, UserID: 1, Title: "event 12 of user 1"},
+		{EventID: 2, UserID: 11, Title: "event 2 of user 11"},
+	}
+
+	for _, event := range events {
+		event.Date.Time = time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)
+
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBuffer(jsonEncode(event)))
+		w := httptest.NewRecorder()
+
+		api.CreateEvent(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("event is not created: %s", w.Body.String())
+		}
+	}
+
+	t.Run("events for day", func(t *testing.T) {
+		for _, event := range events {
+			addr := fmt.Sprintf("http://127.0.0.1:8080/events_for_day?user_id=%d&date=2022-02-01", event.UserID)
+
+			r := httptest.NewRequest("GET", addr, nil)
+			w := httptest.NewRecorder()
+
+			var responseEvent handler.ResultResponse
+
+			api.GetEventsForDay(w, r)
+
+			err := json.Unmarshal(w.Body.Bytes(), &responseEvent)
+			if err != nil {
+				t.Fatalf("cannot read response: %v", err)
+			}
+
+			if len(responseEvent.Result) != 1 || responseEvent.Result[0].Title != event.Title {
+				t.Errorf("events of users are mixed\nShould: %v\nGot: %v", event, responseEvent)
+			}
+		}
+	})
+
+	t.Run("update of another user", func(t *testing.T) {
+		event := events[1]
+		event.UserID = 1
+		event.Version = 1
+		event.Date.Time = time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)
+
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/update_event", bytes.NewBuffer(jsonEncode(event)))
+		w := httptest.NewRecorder()
+
+		api.UpdateEvent(w, r)
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("update of missing event should fail\nShould: %d\nGot: %d %s",
+				http.StatusServiceUnavailable, w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestServerAssignedIDs(t *testing.T) {
+	api := handler.NewHandler()
+
+	event := model.Event{UserID: 1, Title: "event without id"}
+	event.Date.Time = time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)
+
+	// create - sends event and returns id assigned by server
+	create := func(key string) int {
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBuffer(jsonEncode(event)))
+		if key != "" {
+			r.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+
+		var responseEvent handler.ResultResponse
+
+		api.CreateEvent(w, r)
+
+		err := json.Unmarshal(w.Body.Bytes(), &responseEvent)
+		if err != nil || len(responseEvent.Result) != 1 {
+			t.Fatalf("cannot create event: %s", w.Body.String())
+		}
+
+		return responseEvent.Result[0].EventID
+	}
+
+	t.Run("monotonic ids", func(t *testing.T) {
+		first, second := create(""), create("")
+		if first != 1 || second != 2 {
+			t.Errorf("wrong ids\nShould: 1 2\nGot: %d %d", first, second)
+		}
+
+		deleted := event
+		deleted.EventID = second
+
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/delete_event", bytes.NewBuffer(jsonEncode(deleted)))
+		r.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+
+		api.DeleteEvent(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("event is not deleted\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		if id := create(""); id != 3 {
+			t.Errorf("id of deleted event is reused\nShould: 3\nGot: %d", id)
+		}
+	})
+
+	t.Run("idempotent retry", func(t *testing.T) {
+		first, retry := create("retry-key"), create("retry-key")
+		if first != retry {
+			t.Errorf("retry created new event\nShould: %d\nGot: %d", first, retry)
+		}
+
+		if id := create("another-key"); id != first+1 {
+			t.Errorf("wrong id for new key\nShould: %d\nGot: %d", first+1, id)
+		}
+	})
+
+	t.Run("key reused for another body", func(t *testing.T) {
+		event.Title = "another event"
+
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBuffer(jsonEncode(event)))
+		r.Header.Set("Idempotency-Key", "retry-key")
+		w := httptest.NewRecorder()
+
+		api.CreateEvent(w, r)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("wrong status\nShould: %d\nGot: %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+
+	t.Run("oldest key is evicted", func(t *testing.T) {
+		api = handler.NewHandlerWithStore(storage.NewEventStorage(), handler.WithIdempotencyKeys(2))
+		event.Title = "event without id"
+
+		first := create("key-1")
+		create("key-2")
+		last := create("key-3")
+
+		if id := create("key-3"); id != last {
+			t.Errorf("retry of remembered key created new event\nShould: %d\nGot: %d", last, id)
+		}
+		if id := create("key-1"); id == first {
+			t.Errorf("evicted key should be forgotten\nShould: new id\nGot: %d", id)
+		}
+	})
+}
+
 // compare two events
 func compare(event1, event2 model.Event) bool {
 	if event1.UserID != event2.UserID {
@@ -0,0 +1,113 @@
+package dev11
+
+import (
+	"bytes"
+	"encoding/json"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeZones(t *testing.T) {
+	api := handler.NewHandler()
+
+	// create - sends raw json event and returns response
+	create := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "http://127.0.0.1:8080/create_event", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		api.CreateEvent(w, r)
+
+		return w
+	}
+
+	// eventsForDay - returns events of first user for day in time zone
+	eventsForDay := func(date, tz string) []model.Event {
+		r := httptest.NewRequest("GET", "http://127.0.0.1:8080/events_for_day?user_id=1&date="+date+"&tz="+tz, nil)
+		w := httptest.NewRecorder()
+
+		var responseEvent handler.ResultResponse
+
+		api.GetEventsForDay(w, r)
+
+		err := json.Unmarshal(w.Body.Bytes(), &responseEvent)
+		if err != nil {
+			t.Fatalf("cannot read response: %s", w.Body.String())
+		}
+
+		return responseEvent.Result
+	}
+
+	w := create(`{"event_id": 1, "user_id": 1, "title": "night in Moscow", "date": "2022-02-01T01:30", "tz": "Europe/Moscow"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("event is not created: %s", w.Body.String())
+	}
+
+	t.Run("day boundaries", func(t *testing.T) {
+		if events := eventsForDay("2022-02-01", "Europe/Moscow"); len(events) != 1 {
+			t.Errorf("event is not found in Moscow day: %v", events)
+		}
+		if events := eventsForDay("2022-02-01", ""); len(events) != 0 {
+			t.Errorf("event is found in UTC day after it: %v", events)
+		}
+		if events := eventsForDay("2022-01-31", "UTC"); len(events) != 1 {
+			t.Errorf("event is not found in UTC day: %v", events)
+		}
+	})
+
+	t.Run("offset in date", func(t *testing.T) {
+		w := create(`{"event_id": 2, "user_id": 1, "title": "offset", "date": "2022-02-02T10:00:00+03:00"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("event is not created: %s", w.Body.String())
+		}
+
+		events := eventsForDay("2022-02-02T05:00:00Z", "")
+		want := time.Date(2022, 2, 2, 7, 0, 0, 0, time.UTC)
+		if len(events) != 1 || !events[0].Date.Equal(want) {
+			t.Errorf("wrong event\nShould: %v\nGot: %v", want, events)
+		}
+	})
+
+	t.Run("recurrence follows daylight saving time", func(t *testing.T) {
+		w := create(`{"event_id": 3, "user_id": 1, "title": "Berlin weekly", "date": "2022-03-21T10:00",
+			"tz": "Europe/Berlin", "recurrence": {"freq": "WEEKLY"}}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("event is not created: %s", w.Body.String())
+		}
+
+		events := eventsForDay("2022-03-28", "Europe/Berlin")
+		if len(events) != 1 || events[0].Date.Hour() != 10 || events[0].Date.UTC().Hour() != 8 {
+			t.Errorf("wrong occurrence after switch to summer time: %v", events)
+		}
+	})
+
+	t.Run("unknown time zone", func(t *testing.T) {
+		w := create(`{"event_id": 4, "user_id": 1, "date": "2022-02-01T10:00", "tz": "Mars/Olympus"}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("wrong status\nShould: %d\nGot: %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("zone survives encoding", func(t *testing.T) {
+		var event model.Event
+
+		err := json.Unmarshal([]byte(`{"date": "2022-03-21T10:00", "tz": "Europe/Berlin"}`), &event)
+		if err != nil {
+			t.Fatalf("cannot decode event: %v", err)
+		}
+
+		var decoded model.Event
+
+		err = json.Unmarshal(jsonEncode(event), &decoded)
+		if err != nil {
+			t.Fatalf("cannot decode event: %v", err)
+		}
+
+		if decoded.Date.Location().String() != "Europe/Berlin" || !decoded.Date.Equal(event.Date.Time) {
+			t.Errorf("wrong date after encoding\nShould: %v\nGot: %v", event.Date, decoded.Date)
+		}
+	})
+}
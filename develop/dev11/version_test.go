@@ -0,0 +1,88 @@
+package dev11
+
+import (
+	"bytes"
+	"main.go/internal/handler"
+	"main.go/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersions(t *testing.T) {
+	mux := http.NewServeMux()
+	handler.NewHandler().Register(mux)
+
+	// do - sends request through mux with If-Match header if it is given
+	do := func(method, target, ifMatch string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://127.0.0.1:8080"+target, bytes.NewReader(body))
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	event := model.Event{UserID: 1, Title: "sync", Date: model.Date{Time: time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)}}
+
+	w := do("POST", "/create_event", "", jsonEncode(event))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("created event should have the first version\nShould: %d \"1\"\nGot: %d %q", http.StatusOK, w.Code, w.Header().Get("ETag"))
+	}
+
+	event.EventID = 1
+	event.Title = "moved sync"
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		ifMatch string
+		version int
+		want    int
+		etag    string
+	}{
+		{name: "update without version", method: "POST", target: "/update_event", want: http.StatusPreconditionRequired},
+		{name: "delete without version", method: "DELETE", target: "/users/1/events/1", want: http.StatusPreconditionRequired},
+		{name: "wrong If-Match", method: "PUT", target: "/users/1/events/1", ifMatch: "first", want: http.StatusBadRequest},
+		{name: "If-Match does not match body", method: "PUT", target: "/users/1/events/1", ifMatch: `"1"`, version: 2, want: http.StatusBadRequest},
+		{name: "stale If-Match", method: "PUT", target: "/users/1/events/1", ifMatch: `"7"`, want: http.StatusPreconditionFailed, etag: `"1"`},
+		{name: "stale body version", method: "POST", target: "/update_event", version: 7, want: http.StatusConflict, etag: `"1"`},
+		{name: "stale delete", method: "DELETE", target: "/users/1/events/1", ifMatch: `"7"`, want: http.StatusPreconditionFailed, etag: `"1"`},
+		{name: "current If-Match", method: "PUT", target: "/users/1/events/1", ifMatch: `"1"`, want: http.StatusOK, etag: `"2"`},
+		{name: "current body version", method: "POST", target: "/update_event", version: 2, want: http.StatusOK, etag: `"3"`},
+		{name: "replayed update", method: "POST", target: "/update_event", version: 2, want: http.StatusConflict, etag: `"3"`},
+		{name: "any version", method: "PUT", target: "/users/1/events/1", ifMatch: "*", want: http.StatusOK, etag: `"4"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			event.Version = tc.version
+
+			var body []byte
+			if tc.method != "DELETE" {
+				body = jsonEncode(event)
+			}
+
+			w := do(tc.method, tc.target, tc.ifMatch, body)
+			if w.Code != tc.want {
+				t.Errorf("wrong status\nShould: %d\nGot: %d %s", tc.want, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("ETag"); got != tc.etag {
+				t.Errorf("wrong ETag\nShould: %q\nGot: %q", tc.etag, got)
+			}
+		})
+	}
+
+	if w = do("GET", "/users/1/events/1", "", nil); w.Header().Get("ETag") != `"4"` {
+		t.Errorf("event should be read with its version\nShould: %q\nGot: %q", `"4"`, w.Header().Get("ETag"))
+	}
+
+	if w = do("DELETE", "/users/1/events/1", `"4"`, nil); w.Code != http.StatusOK {
+		t.Errorf("event is not deleted\nShould: %d\nGot: %d %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}